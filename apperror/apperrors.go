@@ -70,6 +70,12 @@ func (e *AppError) WithTrace(ctx context.Context) *AppError {
 
 func (e *AppError) Unwrap() error { return e.Err }
 
+// IsRetryable reports whether the same request may succeed later,
+// i.e. the error is internal or caused by rate limiting.
+func (e *AppError) IsRetryable() bool {
+	return e.Type == errInternalSystemCode || e.Type == errTooManyRequestsCode
+}
+
 func (e *AppError) Marshal() []byte {
 	bytes, err := json.Marshal(e)
 	if err != nil {
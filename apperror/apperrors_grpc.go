@@ -25,6 +25,10 @@ func (e *AppError) GRPCStatus() *status.Status {
 		code = codes.PermissionDenied
 	case errConditionFailedCode:
 		code = codes.FailedPrecondition
+	case errTooManyRequestsCode:
+		code = codes.ResourceExhausted
+	case errConflictCode:
+		code = codes.AlreadyExists
 	default:
 		code = codes.Unknown
 	}
@@ -56,6 +56,22 @@ func NewConditionFailedError(systemCode string, options ...Option) *AppError {
 	)
 }
 
+func NewTooManyRequestsError(systemCode string, options ...Option) *AppError {
+	return newAppError(
+		errTooManyRequestsCode,
+		systemCode,
+		options...,
+	)
+}
+
+func NewConflictError(systemCode string, options ...Option) *AppError {
+	return newAppError(
+		errConflictCode,
+		systemCode,
+		options...,
+	)
+}
+
 type Option func(*AppError)
 
 // WithErr Option setter for Err.
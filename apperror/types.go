@@ -14,4 +14,6 @@ const (
 	errUnauthorizedCode
 	errForbiddenCode
 	errConditionFailedCode
+	errTooManyRequestsCode
+	errConflictCode
 )
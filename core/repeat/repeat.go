@@ -3,6 +3,7 @@ package repeat
 import (
 	"context"
 	"errors"
+	"math"
 	"math/rand"
 	"time"
 )
@@ -10,10 +11,11 @@ import (
 type Operation func(context.Context) error
 
 type Config struct {
-	minTimeWait  time.Duration
-	maxTimeWait  time.Duration
-	maxRetries   int
-	errorHandler func(err error) bool
+	minTimeWait   time.Duration
+	maxTimeWait   time.Duration
+	maxRetries    int
+	backoffFactor float64
+	errorHandler  func(err error) bool
 }
 
 const (
@@ -40,6 +42,15 @@ func WithMaxRetries(retries int) OptionSetter {
 	}
 }
 
+// WithBackoffFactor makes the upper bound of the wait grow exponentially from minTimeWait
+// by the factor on every retry, capped by maxTimeWait. Factor <= 1 disables the backoff,
+// so the wait is a random value between minTimeWait and maxTimeWait.
+func WithBackoffFactor(factor float64) OptionSetter {
+	return func(c *Config) {
+		c.backoffFactor = factor
+	}
+}
+
 func WithErrorHandler(handler func(err error) bool) OptionSetter {
 	return func(c *Config) {
 		c.errorHandler = handler
@@ -78,7 +89,15 @@ func Exec(ctx context.Context, op Operation, opts ...OptionSetter) error {
 				return err
 			}
 
-			waitTime := config.minTimeWait + time.Duration(rand.Float64()*float64((config.maxTimeWait-config.minTimeWait)))
+			upperTimeWait := config.maxTimeWait
+			if config.backoffFactor > 1 {
+				backoff := float64(config.minTimeWait) * math.Pow(config.backoffFactor, float64(retries+1))
+				if backoff < float64(config.maxTimeWait) {
+					upperTimeWait = time.Duration(backoff)
+				}
+			}
+
+			waitTime := config.minTimeWait + time.Duration(rand.Float64()*float64((upperTimeWait-config.minTimeWait)))
 			timer := time.NewTimer(waitTime)
 			select {
 			case <-timer.C:
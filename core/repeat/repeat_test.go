@@ -103,3 +103,26 @@ func TestExecMinAndMaxTimeouts(t *testing.T) {
 		}
 	}
 }
+
+func TestExecBackoffFactor(t *testing.T) {
+	op := func(ctx context.Context) error {
+		return errors.New("failed")
+	}
+
+	minTimeout := 10 * time.Millisecond
+	maxTimeout := time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Upper bounds of the waits are 20ms, 40ms and 80ms, far below maxTimeout.
+	startTime := time.Now()
+	if err := Exec(ctx, op, WithMaxRetries(3), WithMinTimeWait(minTimeout), WithMaxTimeWait(maxTimeout), WithBackoffFactor(2)); err == nil || err.Error() != "failed" {
+		t.Fatalf("Expected 'failed' error, got: %v", err)
+	}
+
+	duration := time.Since(startTime)
+	if duration < 3*minTimeout || duration > 140*time.Millisecond+50*time.Millisecond {
+		t.Fatalf("Expected duration to be within %v and %v, got: %v", 3*minTimeout, 190*time.Millisecond, duration)
+	}
+}
@@ -0,0 +1,206 @@
+package mynats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/Kazzess/libraries/apperror"
+	"github.com/nats-io/nats.go"
+)
+
+const defaultHeartbeat = 10 * time.Second
+
+var defaultBackoff = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second, time.Minute}
+
+// ErrPermanent marks handler errors which will not succeed on redelivery.
+var ErrPermanent = errors.New("permanent error")
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+func (e *permanentError) Is(target error) bool { return target == ErrPermanent }
+
+// Permanent wraps err, so the message is terminated instead of redelivered.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err is wrapped by Permanent or is a not retryable apperror,
+// e.g. a validation or not found error.
+func IsPermanent(err error) bool {
+	if errors.Is(err, ErrPermanent) {
+		return true
+	}
+
+	var appErr *apperror.AppError
+	if errors.As(err, &appErr) {
+		return !appErr.IsRetryable()
+	}
+
+	return false
+}
+
+type AckAction string
+
+const (
+	AckActionAck  AckAction = "ack"
+	AckActionNak  AckAction = "nak"
+	AckActionTerm AckAction = "term"
+	// AckActionDeadLetter publishes the message to the dead letter subject and terminates it.
+	AckActionDeadLetter AckAction = "dead_letter"
+)
+
+type AckPolicy struct {
+	backoff       []time.Duration
+	maxDeliveries int
+	heartbeat     time.Duration
+	deadLetter    string
+}
+
+type AckOption func(*AckPolicy)
+
+// WithBackoff sets the redelivery delays of retryable errors. The delay is taken by the
+// delivery number and the last one is used for the rest. Empty schedule redelivers at once.
+func WithBackoff(delays ...time.Duration) AckOption {
+	return func(p *AckPolicy) {
+		p.backoff = delays
+	}
+}
+
+// WithMaxDeliveries terminates the message failed on the delivery number. Zero means no limit.
+func WithMaxDeliveries(maxDeliveries int) AckOption {
+	return func(p *AckPolicy) {
+		p.maxDeliveries = maxDeliveries
+	}
+}
+
+// WithHeartbeat sets how often InProgress is sent while the handler runs, so long handlers
+// don't exceed AckWait. It should be less than AckWait. Non-positive value disables it.
+func WithHeartbeat(interval time.Duration) AckOption {
+	return func(p *AckPolicy) {
+		p.heartbeat = interval
+	}
+}
+
+func NewAckPolicy(options ...AckOption) *AckPolicy {
+	policy := &AckPolicy{
+		backoff:   defaultBackoff,
+		heartbeat: defaultHeartbeat,
+	}
+
+	for _, o := range options {
+		o(policy)
+	}
+
+	return policy
+}
+
+// action returns how to settle the message after the handler returned err on the delivery.
+func (p *AckPolicy) action(err error, delivered uint64) (AckAction, time.Duration) {
+	if err == nil {
+		return AckActionAck, 0
+	}
+
+	// The message isn't failed, so it is redelivered regardless of the delivery number.
+	if errors.Is(err, ErrMessageInProgress) {
+		return AckActionNak, p.delay(1)
+	}
+
+	if IsPermanent(err) || (p.maxDeliveries > 0 && delivered >= uint64(p.maxDeliveries)) {
+		if p.deadLetter != "" {
+			return AckActionDeadLetter, 0
+		}
+
+		return AckActionTerm, 0
+	}
+
+	return AckActionNak, p.delay(delivered)
+}
+
+func (p *AckPolicy) delay(delivered uint64) time.Duration {
+	if len(p.backoff) == 0 {
+		return 0
+	}
+
+	idx := len(p.backoff) - 1
+	if delivered > 0 && delivered <= uint64(len(p.backoff)) {
+		idx = int(delivered) - 1
+	}
+
+	return p.backoff[idx]
+}
+
+// settle acks, naks or terminates the message according to the action.
+func settle(msg *nats.Msg, action AckAction, delay time.Duration) error {
+	switch action {
+	case AckActionAck:
+		return msg.Ack()
+	case AckActionTerm, AckActionDeadLetter:
+		return msg.Term()
+	default:
+		if delay > 0 {
+			return msg.NakWithDelay(delay)
+		}
+
+		return msg.Nak()
+	}
+}
+
+// startHeartbeat sends InProgress every interval until the returned stop is called.
+func (p *AckPolicy) startHeartbeat(msg *nats.Msg) (stop func()) {
+	if p.heartbeat <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(p.heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := msg.InProgress(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func safeHandle(ctx context.Context, handler SubscribeHandler, msg *nats.Msg) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("subscribe handler panic: %v\n%s", p, debug.Stack())
+		}
+	}()
+
+	return handler(ctx, msg)
+}
+
+// numDelivered returns the delivery number of the message, 1 if there is no metadata.
+func numDelivered(msg *nats.Msg) uint64 {
+	metadata, err := msg.Metadata()
+	if err != nil || metadata.NumDelivered == 0 {
+		return 1
+	}
+
+	return metadata.NumDelivered
+}
@@ -0,0 +1,74 @@
+package mynats
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Kazzess/libraries/apperror"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPermanent(t *testing.T) {
+	require.True(t, IsPermanent(Permanent(errors.New("bad payload"))))
+	require.True(t, IsPermanent(fmt.Errorf("handle: %w", Permanent(errors.New("bad payload")))))
+	require.True(t, IsPermanent(apperror.NewValidationError("TEST")))
+	require.False(t, IsPermanent(apperror.NewInternalError("TEST")))
+	require.False(t, IsPermanent(errors.New("timeout")))
+	require.Nil(t, Permanent(nil))
+}
+
+func TestAckPolicy_Action(t *testing.T) {
+	policy := NewAckPolicy(WithBackoff(time.Second, 5*time.Second), WithMaxDeliveries(3))
+	retryable := errors.New("timeout")
+
+	tests := []struct {
+		name      string
+		err       error
+		delivered uint64
+		action    AckAction
+		delay     time.Duration
+	}{
+		{name: "success", delivered: 1, action: AckActionAck},
+		{name: "first failure", err: retryable, delivered: 1, action: AckActionNak, delay: time.Second},
+		{name: "second failure", err: retryable, delivered: 2, action: AckActionNak, delay: 5 * time.Second},
+		{name: "max deliveries", err: retryable, delivered: 3, action: AckActionTerm},
+		{name: "permanent", err: Permanent(retryable), delivered: 1, action: AckActionTerm},
+		{name: "in progress", err: ErrMessageInProgress, delivered: 3, action: AckActionNak, delay: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, delay := policy.action(tt.err, tt.delivered)
+			require.Equal(t, tt.action, action)
+			require.Equal(t, tt.delay, delay)
+		})
+	}
+}
+
+func TestAckPolicy_Delay(t *testing.T) {
+	policy := NewAckPolicy(WithBackoff(time.Second, time.Minute))
+
+	require.Equal(t, time.Second, policy.delay(1))
+	require.Equal(t, time.Minute, policy.delay(2))
+	require.Equal(t, time.Minute, policy.delay(10))
+	require.Equal(t, time.Duration(0), NewAckPolicy(WithBackoff()).delay(1))
+}
+
+func TestAckPolicy_ActionDeadLetter(t *testing.T) {
+	policy := NewAckPolicy(WithMaxDeliveries(2), WithDeadLetter("DLQ.orders"))
+	retryable := errors.New("timeout")
+
+	action, _ := policy.action(retryable, 1)
+	require.Equal(t, AckActionNak, action)
+
+	action, _ = policy.action(retryable, 2)
+	require.Equal(t, AckActionDeadLetter, action)
+
+	action, _ = policy.action(Permanent(retryable), 1)
+	require.Equal(t, AckActionDeadLetter, action)
+
+	action, _ = policy.action(ErrMessageInProgress, 2)
+	require.Equal(t, AckActionNak, action, "duplicate in progress isn't dead-lettered")
+}
@@ -2,11 +2,14 @@ package mynats
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
-	"fmt"
 	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Kazzess/libraries/logging"
 	"github.com/hashicorp/go-multierror"
 	"github.com/nats-io/nats.go"
 	"github.com/pkg/errors"
@@ -32,6 +35,15 @@ type Config struct {
 	password   string
 	debug      bool
 	tracing    bool
+	payload    bool
+	ackPolicy  *AckPolicy
+	codec      Codec
+	codecs     map[string]Codec
+	decodeErr  DecodeErrorHandler
+	timeout    time.Duration
+	hashMsgID  bool
+	connOpts   []nats.Option
+	drainWait  time.Duration
 	health     struct {
 		checker       HealthChecker
 		intervalCheck time.Duration
@@ -60,6 +72,83 @@ func WithTracing(tracing bool) OptionSetter {
 	return func(c *Config) { c.tracing = tracing }
 }
 
+// WithAckPolicy sets how SubscribeAsync settles messages.
+func WithAckPolicy(options ...AckOption) OptionSetter {
+	return func(c *Config) { c.ackPolicy = NewAckPolicy(options...) }
+}
+
+// WithMaxReconnects sets the number of reconnect attempts, 60 by default. Negative value
+// reconnects forever.
+func WithMaxReconnects(maxReconnects int) OptionSetter {
+	return func(c *Config) { c.connOpts = append(c.connOpts, nats.MaxReconnects(maxReconnects)) }
+}
+
+// WithReconnectWait sets the wait between reconnect attempts to the same server, 2 seconds by default.
+func WithReconnectWait(wait time.Duration) OptionSetter {
+	return func(c *Config) { c.connOpts = append(c.connOpts, nats.ReconnectWait(wait)) }
+}
+
+// WithReconnectJitter sets the random wait added to the reconnect wait for plain and TLS
+// connections, 100 milliseconds and 1 second by default.
+func WithReconnectJitter(jitter, jitterTLS time.Duration) OptionSetter {
+	return func(c *Config) { c.connOpts = append(c.connOpts, nats.ReconnectJitter(jitter, jitterTLS)) }
+}
+
+// WithReconnectBufSize sets the size of the buffer keeping messages published while
+// reconnecting, 8MB by default. Negative value disables the buffer.
+func WithReconnectBufSize(size int) OptionSetter {
+	return func(c *Config) { c.connOpts = append(c.connOpts, nats.ReconnectBufSize(size)) }
+}
+
+// WithDrainTimeout sets how long Close waits for pending messages, 30 seconds by default.
+func WithDrainTimeout(timeout time.Duration) OptionSetter {
+	return func(c *Config) { c.drainWait = timeout }
+}
+
+// WithTLS sets the TLS config of the connection.
+func WithTLS(config *tls.Config) OptionSetter {
+	return func(c *Config) { c.connOpts = append(c.connOpts, nats.Secure(config)) }
+}
+
+// WithTLSFiles sets the CA file verifying the server and the client certificate and key files.
+// Empty values are ignored.
+func WithTLSFiles(caFile, certFile, keyFile string) OptionSetter {
+	return func(c *Config) {
+		if caFile != "" {
+			c.connOpts = append(c.connOpts, nats.RootCAs(caFile))
+		}
+
+		if certFile != "" && keyFile != "" {
+			c.connOpts = append(c.connOpts, nats.ClientCert(certFile, keyFile))
+		}
+	}
+}
+
+// WithNKeySeedFile authenticates with the NKey seed from the file.
+func WithNKeySeedFile(seedFile string) OptionSetter {
+	return func(c *Config) {
+		c.connOpts = append(c.connOpts, func(o *nats.Options) error {
+			option, err := nats.NkeyOptionFromSeed(seedFile)
+			if err != nil {
+				return err
+			}
+
+			return option(o)
+		})
+	}
+}
+
+// WithCredentialsFile authenticates with the user JWT and NKey seed from the credentials file.
+func WithCredentialsFile(credsFile string) OptionSetter {
+	return func(c *Config) { c.connOpts = append(c.connOpts, nats.UserCredentials(credsFile)) }
+}
+
+// WithConnectOptions sets other options of the connection. Connection callbacks are
+// overridden by the client.
+func WithConnectOptions(options ...nats.Option) OptionSetter {
+	return func(c *Config) { c.connOpts = append(c.connOpts, options...) }
+}
+
 // WithHealthChecker sets the checker name and health server for the client.
 // Empty name value sets the default name.
 func WithHealthChecker(name string, hc HealthChecker) OptionSetter {
@@ -80,6 +169,9 @@ func NewConfig(servers []string, consumerID string, options ...OptionSetter) *Co
 	config := &Config{
 		servers:    servers,
 		consumerID: consumerID,
+		codec:      JSONCodec,
+		codecs:     defaultCodecs(),
+		payload:    true,
 	}
 
 	for _, option := range options {
@@ -96,13 +188,33 @@ func NewConfig(servers []string, consumerID string, options ...OptionSetter) *Co
 		config.health.intervalCheck = defaultIntervalCheck
 	}
 
+	if config.ackPolicy == nil {
+		config.ackPolicy = NewAckPolicy()
+	}
+
+	if config.timeout <= 0 {
+		config.timeout = defaultRequestTimeout
+	}
+
+	if config.decodeErr == nil {
+		config.decodeErr = defaultDecodeErrorHandler
+	}
+
+	if config.drainWait <= 0 {
+		config.drainWait = nats.DefaultDrainTimeout
+	}
+
 	return config
 }
 
 type Client struct {
-	Config *Config
-	nc     *nats.Conn
-	js     nats.JetStreamContext
+	Config   *Config
+	nc       *nats.Conn
+	js       nats.JetStreamContext
+	closed   chan struct{}
+	draining atomic.Bool
+	mu       sync.Mutex
+	subs     map[*nats.Subscription]struct{}
 }
 
 func NewClient(ctx context.Context, config *Config) (*Client, error) {
@@ -116,8 +228,16 @@ func NewClient(ctx context.Context, config *Config) (*Client, error) {
 		options.Password = config.password
 	}
 
-	// Debug handlers
-	setDebugHandlers(&options, config.debug)
+	options.DrainTimeout = config.drainWait
+
+	for _, option := range config.connOpts {
+		if err := option(&options); err != nil {
+			return nil, errors.Wrap(err, "connect option")
+		}
+	}
+
+	closed := make(chan struct{})
+	setConnectionHandlers(ctx, &options, config, closed)
 
 	nc, err := options.Connect()
 	if err != nil {
@@ -131,7 +251,7 @@ func NewClient(ctx context.Context, config *Config) (*Client, error) {
 		return nil, jetStreamErr
 	}
 
-	return &Client{Config: config, nc: nc, js: js}, nil
+	return &Client{Config: config, nc: nc, js: js, closed: closed}, nil
 }
 
 func (c *Client) Fetch(subject, consumerID string, limit int, opts ...SubscribeOption) (_ []*nats.Msg, err error) {
@@ -169,24 +289,42 @@ func (c *Client) PullSubscribe(subject, consumerID string, opts ...SubscribeOpti
 		return nil, errors.Wrap(err, "pull subscribe")
 	}
 
+	c.track(sub)
+
 	return sub, err
 }
 
+// Close waits for pending async publishes, drains the subscriptions and closes
+// the connection. It waits at most the drain timeout for each step.
+// Durable consumers are kept as in Drain.
 func (c *Client) Close() error {
-	<-c.js.PublishAsyncComplete()
+	if c.nc.IsClosed() {
+		return nil
+	}
+
+	c.draining.Store(true)
 
-	for info := range c.js.Consumers("FOO", nats.MaxWait(10*time.Second)) {
-		fmt.Println("consumer name:", info.Name)
+	select {
+	case <-c.js.PublishAsyncComplete():
+	case <-time.After(c.Config.drainWait):
+		slog.Error("NATS pending async publishes are not completed")
 	}
 
-	if err := c.nc.Flush(); err != nil {
-		return errors.Wrap(err, "nc.Flush")
+	ctx, cancel := context.WithTimeout(context.Background(), c.Config.drainWait)
+	defer cancel()
+
+	if err := c.Drain(ctx); err != nil {
+		slog.Error("NATS subscriptions are not drained", slog.Any("error", err))
 	}
 
-	if !c.nc.IsClosed() {
+	if err := c.nc.Drain(); err != nil {
 		c.nc.Close()
+
+		return errors.Wrap(err, "nc.Drain")
 	}
 
+	<-c.closed
+
 	return nil
 }
 
@@ -238,36 +376,61 @@ func logDebugNatsConnection(c *nats.Conn, msg string) {
 	log.Info(msg)
 }
 
-func setDebugHandlers(options *nats.Options, debug bool) {
-	if options == nil {
-		slog.Error("no nats options")
-		return
+// setConnectionHandlers reports connection events to metrics and the health checker.
+// Connection state is logged in debug mode.
+func setConnectionHandlers(ctx context.Context, options *nats.Options, config *Config, closed chan struct{}) {
+	options.DisconnectedErrCB = func(c *nats.Conn, err error) {
+		// Close is reported by ClosedCB.
+		if c.IsClosed() {
+			return
+		}
+
+		observeConnectionEvent(config, connectionEventDisconnected)
+		setAvailability(config, false)
+
+		logging.WithAttrs(ctx, logging.ErrAttr(err)).Warn("NATS disconnected")
+
+		if config.debug {
+			logDebugNatsConnection(c, "NATS disconnected callback")
+		}
 	}
+	options.ReconnectedCB = func(c *nats.Conn) {
+		observeConnectionEvent(config, connectionEventReconnected)
+		setAvailability(config, true)
 
-	if debug {
-		options.ClosedCB = func(c *nats.Conn) {
+		logging.WithAttrs(ctx, logging.StringAttr("url", c.ConnectedUrlRedacted())).Info("NATS reconnected")
+
+		if config.debug {
+			logDebugNatsConnection(c, "NATS reconnected callback")
+		}
+	}
+	options.ClosedCB = func(c *nats.Conn) {
+		observeConnectionEvent(config, connectionEventClosed)
+		setAvailability(config, false)
+
+		if config.debug {
 			logDebugNatsConnection(c, "NATS closed callback")
 		}
-		options.ReconnectedCB = func(c *nats.Conn) {
-			logDebugNatsConnection(c, "NATS reconnected callback")
+
+		close(closed)
+	}
+	options.AsyncErrorCB = func(c *nats.Conn, sub *nats.Subscription, err error) {
+		event := connectionEventAsyncError
+		if errors.Is(err, nats.ErrSlowConsumer) {
+			event = connectionEventSlowConsumer
 		}
-		options.DisconnectedErrCB = func(c *nats.Conn, err error) {
-			logDebugNatsConnection(c, "NATS disconnected callback")
-			if err == nil {
-				return
-			}
 
-			slog.With(slog.String("error", err.Error())).Info("NATS disconnected")
+		observeConnectionEvent(config, event)
+
+		log := logging.WithAttrs(ctx, logging.ErrAttr(err), logging.StringAttr("event", event))
+		if sub != nil {
+			log = log.With(logging.StringAttr("subject", sub.Subject))
 		}
-		options.AsyncErrorCB = func(c *nats.Conn, subscription *nats.Subscription, err error) {
-			logDebugNatsConnection(c, "NATS reconnected callback")
-			if err == nil {
-				return
-			}
 
-			slog.With(slog.String("error", err.Error())).
-				With(slog.String("subject", subscription.Subject)).
-				Info("Async error in subscription")
+		log.Error("NATS async error")
+
+		if config.debug {
+			logDebugNatsConnection(c, "NATS async error callback")
 		}
 	}
 }
@@ -3,30 +3,25 @@ package mynats
 import (
 	"context"
 	"fmt"
-	"log/slog"
 
 	"github.com/Kazzess/libraries/logging"
 	"github.com/Kazzess/libraries/tracing"
 	"github.com/nats-io/nats.go"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
 func (c *Client) PublishAsync(ctx context.Context, subject string, data []byte, opts ...PublishOption) (err error) {
-	if c.Config.tracing {
-		var span trace.Span
-		ctx, span = tracing.Continue(ctx, "PublishAsync")
-		defer span.End()
-
-		tracing.TraceValue(ctx, "subject", subject)
-		tracing.TraceAny(ctx, "data", data)
-	}
-
 	msg := nats.NewMsg(subject)
 	msg.Data = data
 
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(msg.Header))
+	c.setMsgID(ctx, msg)
+
+	ctx, span := c.startProducerSpan(ctx, trace.SpanKindProducer, msg)
+	defer span.End()
+
+	defer func() {
+		tracing.Error(ctx, err)
+	}()
 
 	ack, err := c.js.PublishMsgAsync(msg, opts...)
 	if err != nil {
@@ -41,6 +36,11 @@ func (c *Client) PublishAsync(ctx context.Context, subject string, data []byte,
 	}
 }
 
+// SubscribeAsync handles messages with the client ack policy: the message is acked on success,
+// terminated on permanent errors or when max deliveries is reached and redelivered with
+// backoff otherwise. Terminated messages are published to the dead letter subject if it is set.
+// ManualAck is always set, since the message is settled after the handler.
+// The subscription is stopped by Drain or Close, the durable consumer is kept.
 func (c *Client) SubscribeAsync(
 	ctx context.Context,
 	subject, consumerID string,
@@ -51,43 +51,83 @@ func (c *Client) SubscribeAsync(
 		consumerID = c.Config.consumerID
 	}
 
-	_, err = c.js.QueueSubscribe(subject, consumerID, func(msg *nats.Msg) {
-		var hErr error
+	opts = append(opts, nats.ManualAck())
+	handlerCtx := context.WithoutCancel(ctx)
 
-		if msg.Header == nil {
-			msg.Header = make(nats.Header)
-		}
+	sub, err := c.js.QueueSubscribe(subject, consumerID, func(msg *nats.Msg) {
+		c.handleMsg(handlerCtx, consumerID, msg, handler)
+	}, opts...)
+	if err != nil {
+		return fmt.Errorf("SubscribeAsync: %w", err)
+	}
 
-		traceCtx := otel.GetTextMapPropagator().
-			Extract(context.Background(), propagation.HeaderCarrier(msg.Header))
+	c.track(sub)
 
-		mdTimestamp, mdConsumer := metricsMetadata(msg)
+	return nil
+}
 
-		ObserveDeliveryTimeMs(msg.Subject, mdConsumer, mdTimestamp, true)
-		observer := ObserveProcessingTimeMs(msg.Subject, consumerID, true)
+// handleMsg runs the handler in the consumer span of the message and settles
+// the message by the ack policy.
+func (c *Client) handleMsg(ctx context.Context, consumerID string, msg *nats.Msg, handler SubscribeHandler) {
+	var hErr error
 
-		if hErr = msg.InProgress(); hErr != nil {
-			slog.With("error", hErr.Error()).Error("set message in progress")
+	policy := c.Config.ackPolicy
 
-			return
-		}
+	ctx, span := c.startConsumerSpan(ctx, trace.SpanKindConsumer, consumerID, msg)
+	defer span.End()
+
+	mdTimestamp, mdConsumer := metricsMetadata(msg)
 
-		hErr = handler(traceCtx, msg)
-		if hErr != nil {
+	ObserveDeliveryTimeMs(msg.Subject, mdConsumer, mdTimestamp, true)
+	observer := ObserveProcessingTimeMs(msg.Subject, consumerID, true)
+
+	stopHeartbeat := policy.startHeartbeat(msg)
+	hErr = safeHandle(ctx, handler, msg)
+	stopHeartbeat()
+
+	delivered := numDelivered(msg)
+	action, delay := policy.action(hErr, delivered)
+
+	span.SetAttributes(attrAckAction.String(string(action)))
+
+	if hErr != nil {
+		tracing.Error(ctx, hErr)
+
+		logging.WithAttrs(
+			ctx,
+			logging.ErrAttr(hErr),
+			logging.StringAttr("data", string(msg.Data)),
+			logging.StringAttr("subject", msg.Subject),
+			logging.AnyAttr("header", msg.Header),
+			logging.Uint64Attr("delivered", delivered),
+			logging.StringAttr("action", string(action)),
+			logging.DurationAttr("delay", delay),
+		).Error("handle message error")
+	}
+
+	if action == AckActionDeadLetter {
+		if dlqErr := c.deadLetter(msg, policy.deadLetter, hErr); dlqErr != nil {
 			logging.WithAttrs(
 				ctx,
-				logging.ErrAttr(hErr),
-				logging.StringAttr("data", string(msg.Data)),
+				logging.ErrAttr(dlqErr),
 				logging.StringAttr("subject", msg.Subject),
-				logging.AnyAttr("header", msg.Header),
-			).Error("subscribe async handle error")
+				logging.StringAttr("dead_letter_subject", policy.deadLetter),
+			).Error("dead letter message error")
+
+			// The message is redelivered instead of being lost.
+			action, delay = AckActionNak, policy.delay(delivered)
 		}
+	}
 
-		observer(&hErr)
-	}, opts...)
-	if err != nil {
-		return fmt.Errorf("SubscribeAsync: %w", err)
+	if ackErr := settle(msg, action, delay); ackErr != nil {
+		logging.WithAttrs(
+			ctx,
+			logging.ErrAttr(ackErr),
+			logging.StringAttr("subject", msg.Subject),
+			logging.StringAttr("action", string(action)),
+		).Error("settle message error")
 	}
 
-	return nil
+	observeAck(msg.Subject, consumerID, action)
+	observer(&hErr)
 }
@@ -0,0 +1,156 @@
+package mynats
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/Kazzess/libraries/errors"
+)
+
+type ConsumerOption func(config *nats.ConsumerConfig)
+
+func WithConsumerDurable(name string) ConsumerOption {
+	return func(c *nats.ConsumerConfig) {
+		c.Durable = name
+	}
+}
+
+func WithConsumerDescription(desc string) ConsumerOption {
+	return func(c *nats.ConsumerConfig) {
+		c.Description = desc
+	}
+}
+
+// WithConsumerFilterSubjects sets the subjects the consumer receives from the stream.
+func WithConsumerFilterSubjects(subjects ...string) ConsumerOption {
+	return func(c *nats.ConsumerConfig) {
+		// The server keeps a single filter in FilterSubject, so it is set the same way.
+		if len(subjects) == 1 {
+			c.FilterSubject = subjects[0]
+			c.FilterSubjects = nil
+
+			return
+		}
+
+		c.FilterSubject = ""
+		c.FilterSubjects = subjects
+	}
+}
+
+func WithConsumerDeliverPolicy(policy nats.DeliverPolicy) ConsumerOption {
+	return func(c *nats.ConsumerConfig) {
+		c.DeliverPolicy = policy
+	}
+}
+
+// WithConsumerAckPolicy sets the ack policy, AckExplicitPolicy by default.
+func WithConsumerAckPolicy(policy nats.AckPolicy) ConsumerOption {
+	return func(c *nats.ConsumerConfig) {
+		c.AckPolicy = policy
+	}
+}
+
+func WithConsumerAckWait(ackWait time.Duration) ConsumerOption {
+	return func(c *nats.ConsumerConfig) {
+		c.AckWait = ackWait
+	}
+}
+
+func WithConsumerMaxDeliver(maxDeliver int) ConsumerOption {
+	return func(c *nats.ConsumerConfig) {
+		c.MaxDeliver = maxDeliver
+	}
+}
+
+// WithConsumerBackOff sets the redelivery delays of messages not acked in time.
+// MaxDeliver must be greater than the number of delays, the first delay overrides AckWait.
+func WithConsumerBackOff(backOff ...time.Duration) ConsumerOption {
+	return func(c *nats.ConsumerConfig) {
+		c.BackOff = backOff
+	}
+}
+
+func WithConsumerMaxAckPending(maxAckPending int) ConsumerOption {
+	return func(c *nats.ConsumerConfig) {
+		c.MaxAckPending = maxAckPending
+	}
+}
+
+func WithConsumerInactiveThreshold(threshold time.Duration) ConsumerOption {
+	return func(c *nats.ConsumerConfig) {
+		c.InactiveThreshold = threshold
+	}
+}
+
+func WithConsumerMaxWaiting(maxWaiting int) ConsumerOption {
+	return func(c *nats.ConsumerConfig) {
+		c.MaxWaiting = maxWaiting
+	}
+}
+
+func WithConsumerMetadata(metadata map[string]string) ConsumerOption {
+	return func(c *nats.ConsumerConfig) {
+		c.Metadata = metadata
+	}
+}
+
+// CreateOrUpdateConsumer creates the durable consumer of the stream or updates the options
+// which differ from the server config, so it may be called on every start. Options not set
+// keep the server values. Fields the server can't update, e.g. the deliver policy, fail.
+func (c *Client) CreateOrUpdateConsumer(stream string, options ...ConsumerOption) (ConfigDiff, error) {
+	config := &nats.ConsumerConfig{
+		AckPolicy: nats.AckExplicitPolicy,
+	}
+
+	for _, option := range options {
+		option(config)
+	}
+
+	if config.Durable == "" {
+		return ConfigDiff{}, errors.New("consumer durable name cannot be empty")
+	}
+
+	// The server uses the first delay as AckWait, so it is not reported as drift.
+	if len(config.BackOff) > 0 {
+		config.AckWait = config.BackOff[0]
+	}
+
+	diff := ConfigDiff{Name: stream + "." + config.Durable}
+
+	info, err := c.js.ConsumerInfo(stream, config.Durable)
+	if errors.Is(err, nats.ErrConsumerNotFound) {
+		if _, err = c.js.AddConsumer(stream, config); err != nil {
+			return diff, errors.Wrap(err, "js.AddConsumer")
+		}
+
+		diff.Created = true
+
+		return diff, nil
+	}
+
+	if err != nil {
+		return diff, errors.Wrap(err, "js.ConsumerInfo")
+	}
+
+	current := info.Config
+
+	diff.Changes = mergeConfig(&current, config)
+
+	// Switching between one and many filters clears the other field.
+	if config.FilterSubject != "" {
+		current.FilterSubjects = nil
+	} else if len(config.FilterSubjects) > 0 {
+		current.FilterSubject = ""
+	}
+
+	if len(diff.Changes) == 0 {
+		return diff, nil
+	}
+
+	if _, err = c.js.UpdateConsumer(stream, &current); err != nil {
+		return diff, errors.Wrap(err, "js.UpdateConsumer")
+	}
+
+	return diff, nil
+}
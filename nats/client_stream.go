@@ -190,16 +190,7 @@ func (c *Client) CreateStream(name string, options ...StreamOption) error {
 		return errors.New("at least one stream subjects must be specified")
 	}
 
-	var targetSubjects []string
-	for _, subject := range config.Subjects {
-		newSubj := subject
-		if !strings.HasPrefix(subject, name) {
-			newSubj = name + "." + subject
-		}
-		targetSubjects = append(targetSubjects, newSubj)
-	}
-
-	config.Subjects = targetSubjects
+	config.Subjects = streamSubjects(name, config.Subjects)
 
 	_, err := c.js.AddStream(config)
 	if err != nil {
@@ -212,3 +203,70 @@ func (c *Client) CreateStream(name string, options ...StreamOption) error {
 
 	return nil
 }
+
+// UpdateStream creates the stream or updates the options which differ from the server config,
+// so it may be called on every start to fix config drift. Options not set keep the server
+// values. Subjects are prefixed with the stream name as in CreateStream.
+func (c *Client) UpdateStream(name string, options ...StreamOption) (ConfigDiff, error) {
+	config := &nats.StreamConfig{
+		Name: name,
+	}
+
+	for _, option := range options {
+		option(config)
+	}
+
+	if config.Name == "" {
+		return ConfigDiff{}, errors.New("stream name cannot be empty")
+	}
+
+	config.Subjects = streamSubjects(config.Name, config.Subjects)
+
+	diff := ConfigDiff{Name: config.Name}
+
+	info, err := c.js.StreamInfo(config.Name)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		if len(config.Subjects) == 0 {
+			return diff, errors.New("at least one stream subjects must be specified")
+		}
+
+		if _, err = c.js.AddStream(config); err != nil {
+			return diff, errors.Wrap(err, "js.AddStream")
+		}
+
+		diff.Created = true
+
+		return diff, nil
+	}
+
+	if err != nil {
+		return diff, errors.Wrap(err, "js.StreamInfo")
+	}
+
+	current := info.Config
+
+	diff.Changes = mergeConfig(&current, config)
+	if len(diff.Changes) == 0 {
+		return diff, nil
+	}
+
+	if _, err = c.js.UpdateStream(&current); err != nil {
+		return diff, errors.Wrap(err, "js.UpdateStream")
+	}
+
+	return diff, nil
+}
+
+// streamSubjects prefixes the subjects with the stream name.
+func streamSubjects(name string, subjects []string) []string {
+	var targetSubjects []string
+	for _, subject := range subjects {
+		newSubj := subject
+		if !strings.HasPrefix(subject, name) {
+			newSubj = name + "." + subject
+		}
+		targetSubjects = append(targetSubjects, newSubj)
+	}
+
+	return targetSubjects
+}
@@ -8,34 +8,35 @@ import (
 
 	"github.com/Kazzess/libraries/errors"
 	"github.com/Kazzess/libraries/tracing"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
 func (c *Client) PublishSync(ctx context.Context, subject string, data []byte, opts ...PublishOption) (err error) {
-	if c.Config.tracing {
-		var span trace.Span
-		ctx, span = tracing.Start(ctx, "PublishSync")
-		defer span.End()
-
-		tracing.TraceValue(ctx, "subject", subject)
-		tracing.TraceAny(ctx, "data", data)
-	}
-
 	msg := nats.NewMsg(subject)
 	msg.Data = data
 
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(msg.Header))
-
-	_, err = c.js.PublishMsg(msg, opts...)
-	if err != nil {
+	if err = c.publishMsg(ctx, msg, opts...); err != nil {
 		return fmt.Errorf("PublishSync: %w", err)
 	}
 
 	return nil
 }
 
+// publishMsg publishes the message with the trace context in headers.
+func (c *Client) publishMsg(ctx context.Context, msg *nats.Msg, opts ...PublishOption) error {
+	c.setMsgID(ctx, msg)
+
+	ctx, span := c.startProducerSpan(ctx, trace.SpanKindProducer, msg)
+	defer span.End()
+
+	_, err := c.js.PublishMsg(msg, opts...)
+	tracing.Error(ctx, err)
+
+	return err
+}
+
+// SubscribeSync handles messages one by one until ctx is done. The subscription
+// is stopped by Drain or Close, the durable consumer is kept.
 func (c *Client) SubscribeSync(
 	ctx context.Context,
 	subject, consumerID string,
@@ -51,6 +52,8 @@ func (c *Client) SubscribeSync(
 		return fmt.Errorf("SubscribeSync: %w", err)
 	}
 
+	c.track(sub)
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -59,28 +62,24 @@ func (c *Client) SubscribeSync(
 		}
 
 		msg, nextMsgErr := sub.NextMsgWithContext(ctx)
-		if nextMsgErr != nil {
-			return errors.Wrap(nextMsgErr, "NextMsgWithContext")
+		if c.drained(nextMsgErr) {
+			return nil
 		}
 
-		var span trace.Span
-		ctx, span = tracing.Start(ctx, "SubscribeSync")
-		tracing.TraceValue(ctx, "subject", subject)
-
-		if msg.Header == nil {
-			msg.Header = make(nats.Header)
+		if nextMsgErr != nil {
+			return errors.Wrap(nextMsgErr, "NextMsgWithContext")
 		}
 
-		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(msg.Header))
+		msgCtx, span := c.startConsumerSpan(ctx, trace.SpanKindConsumer, consumerID, msg)
 
 		mdTimestamp, mdConsumer := metricsMetadata(msg)
 
 		ObserveDeliveryTimeMs(msg.Subject, mdConsumer, mdTimestamp, false)
 		observer := ObserveProcessingTimeMs(msg.Subject, consumerID, false)
 
-		err = handler(ctx, msg)
+		err = handler(msgCtx, msg)
 		if err != nil {
-			span.SetAttributes(MessageAttributes(msg)...)
+			tracing.Error(msgCtx, err)
 			span.End()
 
 			return errors.Wrap(err, "process handler")
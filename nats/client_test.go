@@ -1,13 +1,17 @@
 package mynats
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"log"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/nats-io/nats.go"
 
+	"github.com/Kazzess/libraries/apperror"
 	"github.com/Kazzess/libraries/core/rnd"
 	"github.com/Kazzess/libraries/errors"
 	"github.com/nats-io/nats-server/v2/server"
@@ -52,7 +56,7 @@ func TestClient_PublishSyncAndFetch(t *testing.T) {
 
 	config := NewConfig([]string{natsServer}, "test-consumer")
 
-	client, err := NewClient(config)
+	client, err := NewClient(context.Background(), config)
 	require.NoError(t, err)
 	require.NotNil(t, client)
 
@@ -88,7 +92,7 @@ func TestClient_SubscribeSync(t *testing.T) {
 
 	config := NewConfig([]string{natsServer}, "test-consumer")
 
-	client, err := NewClient(config)
+	client, err := NewClient(context.Background(), config)
 	require.NoError(t, err)
 	require.NotNil(t, client)
 
@@ -124,7 +128,7 @@ func TestClient_PublishAsync(t *testing.T) {
 
 	config := NewConfig([]string{natsServer}, "test-consumer")
 
-	client, err := NewClient(config)
+	client, err := NewClient(context.Background(), config)
 	require.NoError(t, err)
 	require.NotNil(t, client)
 
@@ -149,7 +153,7 @@ func TestClient_SubscribeAsync(t *testing.T) {
 
 	config := NewConfig([]string{natsServer}, "test-consumer")
 
-	client, err := NewClient(config)
+	client, err := NewClient(context.Background(), config)
 	require.NoError(t, err)
 	require.NotNil(t, client)
 
@@ -187,3 +191,673 @@ func TestClient_SubscribeAsync(t *testing.T) {
 		t.Fatalf("Timed out waiting for async message receipt")
 	}
 }
+
+func TestClient_SubscribeAsyncConsumerOptions(t *testing.T) {
+	s := runNATSServer()
+	defer s.Shutdown()
+
+	client, err := NewClient(context.Background(), NewConfig([]string{natsServer}, "test-consumer"))
+	require.NoError(t, err)
+
+	streamName := "testStreamOptions"
+	err = client.CreateStream(streamName, WithSubjects("testO"))
+	require.NoError(t, err)
+
+	subject := streamName + ".testO"
+
+	err = client.PublishSync(context.Background(), subject, []byte("before"))
+	require.NoError(t, err)
+
+	received := make(chan string, 2)
+
+	err = client.SubscribeAsync(
+		context.Background(),
+		subject,
+		"test-options-consumer",
+		func(ctx context.Context, msg *nats.Msg) error {
+			received <- string(msg.Data)
+			return nil
+		},
+		nats.DeliverNew(),
+		nats.AckWait(time.Minute),
+	)
+	require.NoError(t, err)
+
+	info, err := client.js.ConsumerInfo(streamName, "test-options-consumer")
+	require.NoError(t, err)
+	require.Equal(t, nats.DeliverNewPolicy, info.Config.DeliverPolicy)
+	require.Equal(t, time.Minute, info.Config.AckWait)
+
+	err = client.PublishSync(context.Background(), subject, []byte("after"))
+	require.NoError(t, err)
+
+	select {
+	case data := <-received:
+		require.Equal(t, "after", data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+
+	require.NoError(t, client.Close())
+
+	// The consumer created by the subscription is kept after Close.
+	other, err := NewClient(context.Background(), NewConfig([]string{natsServer}, "test-consumer"))
+	require.NoError(t, err)
+
+	defer other.Close()
+
+	_, err = other.js.ConsumerInfo(streamName, "test-options-consumer")
+	require.NoError(t, err)
+}
+
+func TestClient_SubscribeAsyncRedelivery(t *testing.T) {
+	s := runNATSServer()
+	defer s.Shutdown()
+
+	config := NewConfig(
+		[]string{natsServer},
+		"test-consumer",
+		WithAckPolicy(WithBackoff(10*time.Millisecond), WithMaxDeliveries(3)),
+	)
+
+	client, err := NewClient(context.Background(), config)
+	require.NoError(t, err)
+	require.NotNil(t, client)
+
+	defer client.Close()
+
+	streamName := "testStreamRedelivery"
+	err = client.CreateStream(streamName, WithSubjects("testE"))
+	require.NoError(t, err)
+
+	subject := streamName + ".testE"
+	err = client.PublishSync(context.Background(), subject, []byte("retry me"))
+	require.NoError(t, err)
+
+	deliveries := make(chan uint64, 3)
+
+	err = client.SubscribeAsync(
+		context.Background(),
+		subject,
+		"test-consumer",
+		func(ctx context.Context, msg *nats.Msg) error {
+			metadata, mdErr := msg.Metadata()
+			require.NoError(t, mdErr)
+
+			deliveries <- metadata.NumDelivered
+
+			if metadata.NumDelivered == 1 {
+				return errors.New("temporary failure")
+			}
+
+			return nil
+		},
+	)
+	require.NoError(t, err)
+
+	for want := uint64(1); want <= 2; want++ {
+		select {
+		case delivered := <-deliveries:
+			require.Equal(t, want, delivered)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for delivery %d", want)
+		}
+	}
+
+	select {
+	case delivered := <-deliveries:
+		t.Fatalf("Unexpected delivery %d of acked message", delivered)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestClient_SubscribeAsyncDeadLetter(t *testing.T) {
+	s := runNATSServer()
+	defer s.Shutdown()
+
+	config := NewConfig(
+		[]string{natsServer},
+		"test-consumer",
+		WithAckPolicy(WithBackoff(10*time.Millisecond), WithMaxDeliveries(2), WithDeadLetter("DLQ.testG")),
+	)
+
+	client, err := NewClient(context.Background(), config)
+	require.NoError(t, err)
+	require.NotNil(t, client)
+
+	defer client.Close()
+
+	streamName := "testStreamDeadLetter"
+	err = client.CreateStream(streamName, WithSubjects("testG"))
+	require.NoError(t, err)
+
+	err = client.CreateStream("DLQ", WithSubjects("testG"))
+	require.NoError(t, err)
+
+	subject := streamName + ".testG"
+	message := []byte("poison")
+	err = client.PublishSync(context.Background(), subject, message)
+	require.NoError(t, err)
+
+	var failing atomic.Bool
+	failing.Store(true)
+
+	delivered := make(chan struct{}, 10)
+
+	err = client.SubscribeAsync(
+		context.Background(),
+		subject,
+		"test-consumer",
+		func(ctx context.Context, msg *nats.Msg) error {
+			if failing.Load() {
+				return errors.New("always fails")
+			}
+
+			delivered <- struct{}{}
+
+			return nil
+		},
+	)
+	require.NoError(t, err)
+
+	dlq, err := client.Fetch("DLQ.testG", "dlq-inspector", 1)
+	require.NoError(t, err)
+	require.Len(t, dlq, 1)
+	require.Equal(t, message, dlq[0].Data)
+	require.Equal(t, subject, dlq[0].Header.Get(HeaderDeadLetterSubject))
+	require.Equal(t, "2", dlq[0].Header.Get(HeaderDeadLetterDelivered))
+	require.Equal(t, "always fails", dlq[0].Header.Get(HeaderDeadLetterError))
+
+	failing.Store(false)
+
+	replayed, err := client.ReplayDeadLetters(context.Background(), "DLQ.testG", "dlq-replay", 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, replayed)
+
+	select {
+	case <-delivered:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for replayed message")
+	}
+}
+
+func TestPublishAndSubscribeTyped(t *testing.T) {
+	s := runNATSServer()
+	defer s.Shutdown()
+
+	decodeErrors := make(chan error, 1)
+
+	config := NewConfig(
+		[]string{natsServer},
+		"test-consumer",
+		WithCodec(MsgpackCodec),
+		WithDecodeErrorHandler(func(ctx context.Context, msg *nats.Msg, err error) error {
+			decodeErrors <- err
+			return nil
+		}),
+	)
+
+	client, err := NewClient(context.Background(), config)
+	require.NoError(t, err)
+	require.NotNil(t, client)
+
+	defer client.Close()
+
+	streamName := "testStreamTyped"
+	err = client.CreateStream(streamName, WithSubjects("testH"))
+	require.NoError(t, err)
+
+	subject := streamName + ".testH"
+	event := testEvent{ID: 7, Name: "created"}
+
+	err = Publish(context.Background(), client, subject, event)
+	require.NoError(t, err)
+
+	err = client.PublishSync(context.Background(), subject, []byte("not msgpack"))
+	require.NoError(t, err)
+
+	received := make(chan Meta, 1)
+
+	err = Subscribe(
+		context.Background(),
+		client,
+		subject,
+		"test-consumer",
+		func(ctx context.Context, v testEvent, meta Meta) error {
+			require.Equal(t, event, v)
+			received <- meta
+			return nil
+		},
+	)
+	require.NoError(t, err)
+
+	select {
+	case meta := <-received:
+		require.Equal(t, ContentTypeMsgpack, meta.ContentType)
+		require.Equal(t, "mynats.testEvent", meta.Schema)
+		require.NotNil(t, meta.Metadata)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for typed message")
+	}
+
+	select {
+	case decodeErr := <-decodeErrors:
+		require.Error(t, decodeErr)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for decode error")
+	}
+}
+
+func TestClient_Consume(t *testing.T) {
+	s := runNATSServer()
+	defer s.Shutdown()
+
+	config := NewConfig([]string{natsServer}, "test-consumer")
+
+	client, err := NewClient(context.Background(), config)
+	require.NoError(t, err)
+	require.NotNil(t, client)
+
+	defer client.Close()
+
+	streamName := "testStreamConsume"
+	err = client.CreateStream(streamName, WithSubjects("testI"))
+	require.NoError(t, err)
+
+	subject := streamName + ".testI"
+	total := 20
+
+	err = client.Consume(context.Background(), subject, "test-pull-consumer", nil)
+	require.ErrorIs(t, err, nats.ErrConsumerNotFound)
+
+	_, err = client.CreateOrUpdateConsumer(
+		streamName,
+		WithConsumerDurable("test-pull-consumer"),
+		WithConsumerFilterSubjects(subject),
+	)
+	require.NoError(t, err)
+
+	for i := 0; i < total; i++ {
+		err = client.PublishSync(context.Background(), subject, []byte("work"))
+		require.NoError(t, err)
+	}
+
+	var (
+		handled     atomic.Int32
+		running     atomic.Int32
+		maxRunning  atomic.Int32
+		concurrency = 4
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- client.Consume(
+			ctx,
+			subject,
+			"test-pull-consumer",
+			func(ctx context.Context, msg *nats.Msg) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+
+				for {
+					current := maxRunning.Load()
+					if n <= current || maxRunning.CompareAndSwap(current, n) {
+						break
+					}
+				}
+
+				time.Sleep(20 * time.Millisecond)
+				handled.Add(1)
+
+				return nil
+			},
+			WithConcurrency(concurrency),
+			WithConsumeBatchSize(5),
+			WithConsumeMaxWait(200*time.Millisecond),
+		)
+	}()
+
+	require.Eventually(t, func() bool {
+		return handled.Load() == int32(total)
+	}, 5*time.Second, 20*time.Millisecond)
+
+	cancel()
+
+	select {
+	case err = <-done:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for consume to stop")
+	}
+
+	require.LessOrEqual(t, maxRunning.Load(), int32(concurrency))
+	require.Zero(t, running.Load())
+
+	_, err = client.js.ConsumerInfo(streamName, "test-pull-consumer")
+	require.NoError(t, err)
+}
+
+func TestClient_Reconcile(t *testing.T) {
+	s := runNATSServer()
+	defer s.Shutdown()
+
+	config := NewConfig([]string{natsServer}, "test-consumer")
+
+	client, err := NewClient(context.Background(), config)
+	require.NoError(t, err)
+	require.NotNil(t, client)
+
+	defer client.Close()
+
+	streamName := "testStreamReconcile"
+
+	diff, err := client.UpdateStream(streamName, WithSubjects("testJ"), WithMaxMsgs(10))
+	require.NoError(t, err)
+	require.True(t, diff.Created)
+
+	diff, err = client.UpdateStream(streamName, WithSubjects("testJ"), WithMaxMsgs(10))
+	require.NoError(t, err)
+	require.False(t, diff.Changed())
+
+	diff, err = client.UpdateStream(streamName, WithSubjects("testJ"), WithMaxMsgs(20))
+	require.NoError(t, err)
+	require.Equal(t, []ConfigChange{{Field: "MaxMsgs", From: "10", To: "20"}}, diff.Changes)
+
+	consumerOptions := []ConsumerOption{
+		WithConsumerDurable("billing"),
+		WithConsumerFilterSubjects(streamName + ".testJ"),
+		WithConsumerAckWait(10 * time.Second),
+		WithConsumerMaxDeliver(5),
+		WithConsumerBackOff(time.Second, 5*time.Second),
+		WithConsumerMaxAckPending(100),
+		WithConsumerInactiveThreshold(time.Hour),
+	}
+
+	diff, err = client.CreateOrUpdateConsumer(streamName, consumerOptions...)
+	require.NoError(t, err)
+	require.True(t, diff.Created)
+
+	diff, err = client.CreateOrUpdateConsumer(streamName, consumerOptions...)
+	require.NoError(t, err)
+	require.False(t, diff.Changed(), diff.String())
+
+	diff, err = client.CreateOrUpdateConsumer(streamName, append(consumerOptions, WithConsumerMaxDeliver(10))...)
+	require.NoError(t, err)
+	require.Equal(t, []ConfigChange{{Field: "MaxDeliver", From: "5", To: "10"}}, diff.Changes)
+}
+
+func TestClient_Request(t *testing.T) {
+	s := runNATSServer()
+	defer s.Shutdown()
+
+	config := NewConfig([]string{natsServer}, "test-consumer")
+
+	client, err := NewClient(context.Background(), config)
+	require.NoError(t, err)
+	require.NotNil(t, client)
+
+	defer client.Close()
+
+	service, err := client.AddService(context.Background(), "orders", "1.0.0")
+	require.NoError(t, err)
+
+	defer service.Stop()
+
+	err = service.Handle("echo", "orders.echo", func(ctx context.Context, msg *Msg) ([]byte, error) {
+		return msg.Data, nil
+	})
+	require.NoError(t, err)
+
+	err = service.Handle("get", "orders.get", func(ctx context.Context, msg *Msg) ([]byte, error) {
+		return nil, apperror.NewNotFoundError("ORD", apperror.WithMessage("order not found"))
+	})
+	require.NoError(t, err)
+
+	reply, err := client.Request(context.Background(), "orders.echo", []byte("ping"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("ping"), reply.Data)
+
+	_, err = client.Request(context.Background(), "orders.get", []byte("42"))
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, "order not found", appErr.Message)
+
+	_, err = client.Request(context.Background(), "orders.missing", nil)
+	require.ErrorIs(t, err, nats.ErrNoResponders)
+
+	require.Len(t, service.Info().Endpoints, 2)
+}
+
+func TestClient_KeyValue(t *testing.T) {
+	s := runNATSServer()
+	defer s.Shutdown()
+
+	config := NewConfig([]string{natsServer}, "test-consumer")
+
+	client, err := NewClient(context.Background(), config)
+	require.NoError(t, err)
+	require.NotNil(t, client)
+
+	defer client.Close()
+
+	ctx := context.Background()
+
+	kv, err := client.KeyValue("settings", WithKeyValueHistory(5), WithKeyValueTTL(time.Hour))
+	require.NoError(t, err)
+
+	_, err = kv.Get(ctx, "feature")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	revision, err := kv.Create(ctx, "feature", []byte(`{"id":1,"name":"on"}`))
+	require.NoError(t, err)
+
+	_, err = kv.Create(ctx, "feature", []byte(`{"id":1,"name":"on"}`))
+	require.ErrorIs(t, err, ErrKeyExists)
+
+	_, err = kv.Update(ctx, "feature", []byte(`{"id":1,"name":"off"}`), revision+10)
+	require.ErrorIs(t, err, ErrRevisionMismatch)
+
+	revision, err = kv.Update(ctx, "feature", []byte(`{"id":1,"name":"off"}`), revision)
+	require.NoError(t, err)
+
+	entry, err := kv.Get(ctx, "feature")
+	require.NoError(t, err)
+	require.Equal(t, revision, entry.Revision())
+
+	keys, err := kv.Keys(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"feature"}, keys)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	updates := make(chan KeyValueUpdate[testEvent], 10)
+
+	go func() {
+		_ = WatchKeyValue(watchCtx, kv, "feature", func(ctx context.Context, update KeyValueUpdate[testEvent]) error {
+			updates <- update
+			return nil
+		})
+	}()
+
+	select {
+	case update := <-updates:
+		require.Equal(t, testEvent{ID: 1, Name: "off"}, update.Value)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for current value")
+	}
+
+	require.NoError(t, kv.Delete(ctx, "feature"))
+
+	select {
+	case update := <-updates:
+		require.Equal(t, KeyValueDelete, update.Operation)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for delete")
+	}
+}
+
+func TestClient_ObjectStore(t *testing.T) {
+	s := runNATSServer()
+	defer s.Shutdown()
+
+	config := NewConfig([]string{natsServer}, "test-consumer")
+
+	client, err := NewClient(context.Background(), config)
+	require.NoError(t, err)
+	require.NotNil(t, client)
+
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := client.ObjectStore("reports", WithObjectStoreDescription("monthly reports"))
+	require.NoError(t, err)
+
+	data := bytes.Repeat([]byte("report"), 100000)
+
+	// The test server max payload is less than the default chunk size.
+	meta := ObjectMeta{
+		Name:     "2026-10.csv",
+		Metadata: map[string]string{"owner": "billing"},
+		Opts:     &nats.ObjectMetaOptions{ChunkSize: 32 * 1024},
+	}
+
+	info, err := store.Put(ctx, meta, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, uint64(len(data)), info.Size)
+
+	result, err := store.Get(ctx, "2026-10.csv")
+	require.NoError(t, err)
+
+	read, err := io.ReadAll(result)
+	require.NoError(t, err)
+	require.NoError(t, result.Close())
+	require.Equal(t, data, read)
+
+	err = store.UpdateMeta(ctx, "2026-10.csv", ObjectMeta{Name: "2026-10.csv", Description: "October"})
+	require.NoError(t, err)
+
+	info, err = store.Info(ctx, "2026-10.csv")
+	require.NoError(t, err)
+	require.Equal(t, "October", info.Description)
+
+	objects, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+
+	require.NoError(t, store.Delete(ctx, "2026-10.csv"))
+
+	_, err = store.Info(ctx, "2026-10.csv")
+	require.ErrorIs(t, err, ErrObjectNotFound)
+}
+
+func TestClient_DrainAndClose(t *testing.T) {
+	s := runNATSServer()
+	defer s.Shutdown()
+
+	config := NewConfig([]string{natsServer}, "test-consumer", WithMaxReconnects(1), WithDrainTimeout(5*time.Second))
+
+	client, err := NewClient(context.Background(), config)
+	require.NoError(t, err)
+	require.NotNil(t, client)
+
+	streamName := "testStreamDrain"
+	err = client.CreateStream(streamName, WithSubjects("testJ", "testK"))
+	require.NoError(t, err)
+
+	var handled atomic.Int32
+
+	err = client.SubscribeAsync(
+		context.Background(),
+		streamName+".testJ",
+		"test-drain-consumer",
+		func(ctx context.Context, msg *nats.Msg) error {
+			time.Sleep(10 * time.Millisecond)
+			handled.Add(1)
+
+			return nil
+		},
+	)
+	require.NoError(t, err)
+
+	_, err = client.CreateOrUpdateConsumer(
+		streamName,
+		WithConsumerDurable("test-drain-pull-consumer"),
+		WithConsumerFilterSubjects(streamName+".testK"),
+	)
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- client.Consume(
+			context.Background(),
+			streamName+".testK",
+			"test-drain-pull-consumer",
+			func(ctx context.Context, msg *nats.Msg) error { return nil },
+			WithConsumeMaxWait(100*time.Millisecond),
+		)
+	}()
+
+	total := 10
+
+	for i := 0; i < total; i++ {
+		err = client.PublishSync(context.Background(), streamName+".testJ", []byte("work"))
+		require.NoError(t, err)
+	}
+
+	// Drain handles the messages delivered to the client.
+	require.Eventually(t, func() bool {
+		info, infoErr := client.js.ConsumerInfo(streamName, "test-drain-consumer")
+		return infoErr == nil && info.Delivered.Consumer >= uint64(total)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, client.Drain(ctx))
+	require.Empty(t, client.subscriptions())
+	require.Equal(t, int32(total), handled.Load())
+
+	select {
+	case err = <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Consume is not stopped by Drain")
+	}
+
+	// The connection stays open for publishing after Drain.
+	err = client.PublishSync(context.Background(), streamName+".testJ", []byte("after drain"))
+	require.NoError(t, err)
+
+	require.NoError(t, client.Close())
+	require.True(t, client.nc.IsClosed())
+	require.NoError(t, client.Close())
+
+	// The durable consumers are kept after Close.
+	other, err := NewClient(context.Background(), NewConfig([]string{natsServer}, "test-consumer"))
+	require.NoError(t, err)
+
+	defer other.Close()
+
+	for _, consumer := range []string{"test-drain-consumer", "test-drain-pull-consumer"} {
+		_, err = other.js.ConsumerInfo(streamName, consumer)
+		require.NoError(t, err)
+	}
+}
+
+func TestNewClient_ConnectOptions(t *testing.T) {
+	s := runNATSServer()
+	defer s.Shutdown()
+
+	_, err := NewClient(context.Background(), NewConfig([]string{natsServer}, "", WithCredentialsFile("missing.creds")))
+	require.Error(t, err)
+
+	_, err = NewClient(context.Background(), NewConfig([]string{natsServer}, "", WithNKeySeedFile("missing.nk")))
+	require.Error(t, err)
+}
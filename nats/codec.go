@@ -0,0 +1,113 @@
+package mynats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/protobuf"
+	ContentTypeMsgpack  = "application/msgpack"
+)
+
+var (
+	ErrUnknownContentType = errors.New("unknown content type")
+	ErrNotProtoMessage    = errors.New("value is not a proto message")
+)
+
+// Codec marshals typed messages. The content type is sent in the Content-Type header
+// and selects the codec on decoding.
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	JSONCodec     Codec = jsonCodec{}
+	ProtobufCodec Codec = protobufCodec{}
+	MsgpackCodec  Codec = msgpackCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return ContentTypeJSON }
+
+func (jsonCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string { return ContentTypeProtobuf }
+
+func (protobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrNotProtoMessage, v)
+	}
+
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrNotProtoMessage, v)
+	}
+
+	return proto.Unmarshal(data, m)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string { return ContentTypeMsgpack }
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) { return msgpack.Marshal(v) }
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error { return msgpack.Unmarshal(data, v) }
+
+// WithCodec sets the codec used by Publish, JSON by default. The codec is also registered
+// for decoding, so custom codecs may be used.
+func WithCodec(codec Codec) OptionSetter {
+	return func(c *Config) {
+		c.codec = codec
+		c.codecs[codec.ContentType()] = codec
+	}
+}
+
+// WithCodecs registers codecs for decoding in addition to JSON, protobuf and msgpack.
+func WithCodecs(codecs ...Codec) OptionSetter {
+	return func(c *Config) {
+		for _, codec := range codecs {
+			c.codecs[codec.ContentType()] = codec
+		}
+	}
+}
+
+func defaultCodecs() map[string]Codec {
+	return map[string]Codec{
+		ContentTypeJSON:     JSONCodec,
+		ContentTypeProtobuf: ProtobufCodec,
+		ContentTypeMsgpack:  MsgpackCodec,
+	}
+}
+
+// codecFor returns the codec of the content type. Empty content type means the default codec.
+func (c *Config) codecFor(contentType string) (Codec, error) {
+	if contentType == "" {
+		return c.codec, nil
+	}
+
+	codec, ok := c.codecs[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownContentType, contentType)
+	}
+
+	return codec, nil
+}
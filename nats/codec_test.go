@@ -0,0 +1,75 @@
+package mynats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type testEvent struct {
+	ID   int    `json:"id" msgpack:"id"`
+	Name string `json:"name" msgpack:"name"`
+}
+
+type testVersionedEvent struct{}
+
+func (testVersionedEvent) MessageSchema() (string, string) { return "orders.created", "v2" }
+
+func TestCodecs(t *testing.T) {
+	event := testEvent{ID: 1, Name: "created"}
+
+	for _, codec := range []Codec{JSONCodec, MsgpackCodec} {
+		t.Run(codec.ContentType(), func(t *testing.T) {
+			data, err := codec.Marshal(event)
+			require.NoError(t, err)
+
+			decoded, err := decode[testEvent](codec, data)
+			require.NoError(t, err)
+			require.Equal(t, event, decoded)
+
+			decodedPtr, err := decode[*testEvent](codec, data)
+			require.NoError(t, err)
+			require.Equal(t, event, *decodedPtr)
+		})
+	}
+
+	t.Run(ContentTypeProtobuf, func(t *testing.T) {
+		data, err := ProtobufCodec.Marshal(wrapperspb.String("created"))
+		require.NoError(t, err)
+
+		decoded, err := decode[*wrapperspb.StringValue](ProtobufCodec, data)
+		require.NoError(t, err)
+		require.Equal(t, "created", decoded.GetValue())
+
+		_, err = ProtobufCodec.Marshal(event)
+		require.ErrorIs(t, err, ErrNotProtoMessage)
+	})
+}
+
+func TestConfig_CodecFor(t *testing.T) {
+	config := NewConfig(nil, "", WithCodec(MsgpackCodec))
+
+	codec, err := config.codecFor("")
+	require.NoError(t, err)
+	require.Equal(t, MsgpackCodec, codec)
+
+	codec, err = config.codecFor(ContentTypeProtobuf)
+	require.NoError(t, err)
+	require.Equal(t, ProtobufCodec, codec)
+
+	_, err = config.codecFor("text/xml")
+	require.ErrorIs(t, err, ErrUnknownContentType)
+}
+
+func TestSchemaOf(t *testing.T) {
+	name, version := schemaOf(testVersionedEvent{})
+	require.Equal(t, "orders.created", name)
+	require.Equal(t, "v2", version)
+
+	name, _ = schemaOf(wrapperspb.String("created"))
+	require.Equal(t, "google.protobuf.StringValue", name)
+
+	name, _ = schemaOf(&testEvent{})
+	require.Equal(t, "mynats.testEvent", name)
+}
@@ -0,0 +1,203 @@
+package mynats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Kazzess/libraries/logging"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	defaultConsumeBatchSize  = 10
+	defaultConsumeMaxWait    = 5 * time.Second
+	defaultConsumeRetryDelay = time.Second
+)
+
+type ConsumeConfig struct {
+	batchSize   int
+	maxWait     time.Duration
+	concurrency int
+	subOpts     []SubscribeOption
+}
+
+type ConsumeOption func(*ConsumeConfig)
+
+// WithConsumeBatchSize sets the maximum number of messages fetched at once, 10 by default.
+func WithConsumeBatchSize(size int) ConsumeOption {
+	return func(c *ConsumeConfig) {
+		c.batchSize = size
+	}
+}
+
+// WithConsumeMaxWait sets how long a fetch waits for messages, 5 seconds by default.
+func WithConsumeMaxWait(maxWait time.Duration) ConsumeOption {
+	return func(c *ConsumeConfig) {
+		c.maxWait = maxWait
+	}
+}
+
+// WithConcurrency sets the number of messages handled in parallel, 1 by default.
+func WithConcurrency(concurrency int) ConsumeOption {
+	return func(c *ConsumeConfig) {
+		c.concurrency = concurrency
+	}
+}
+
+// WithConsumeSubscribeOptions sets the options of the pull subscription.
+func WithConsumeSubscribeOptions(opts ...SubscribeOption) ConsumeOption {
+	return func(c *ConsumeConfig) {
+		c.subOpts = opts
+	}
+}
+
+// Consume handles messages of the durable pull consumer until ctx is done. The consumer must
+// exist, e.g. created by CreateOrUpdateConsumer, Consume binds to it and keeps it on exit.
+// Messages are fetched only for idle workers, so slow handlers hold messages in the stream
+// instead of the client buffer. Messages are settled by the ack policy as in SubscribeAsync.
+// On cancel, Drain or Close Consume stops fetching and waits for the handlers in flight.
+func (c *Client) Consume(
+	ctx context.Context,
+	subject, consumerID string,
+	handler SubscribeHandler,
+	options ...ConsumeOption,
+) error {
+	if consumerID == "" {
+		consumerID = c.Config.consumerID
+	}
+
+	config := &ConsumeConfig{
+		batchSize:   defaultConsumeBatchSize,
+		maxWait:     defaultConsumeMaxWait,
+		concurrency: 1,
+	}
+
+	for _, o := range options {
+		o(config)
+	}
+
+	config.concurrency = max(config.concurrency, 1)
+	config.batchSize = max(config.batchSize, 1)
+
+	stream, err := c.js.StreamNameBySubject(subject)
+	if err != nil {
+		return fmt.Errorf("Consume: %w", err)
+	}
+
+	// A bound subscription doesn't delete the consumer on unsubscribe.
+	subOpts := append(slices.Clone(config.subOpts), nats.Bind(stream, consumerID))
+
+	sub, err := c.js.PullSubscribe(subject, consumerID, subOpts...)
+	if err != nil {
+		return fmt.Errorf("Consume: %w", err)
+	}
+
+	c.track(sub)
+
+	defer func() {
+		c.untrack(sub)
+		_ = sub.Unsubscribe()
+	}()
+
+	var (
+		wg         sync.WaitGroup
+		workers    = make(chan struct{}, config.concurrency)
+		handlerCtx = context.WithoutCancel(ctx)
+	)
+
+	defer wg.Wait()
+
+	for {
+		idle, ok := acquireWorkers(ctx, workers, config.batchSize)
+		if !ok {
+			return nil
+		}
+
+		messages, fetchErr := fetchBatch(ctx, sub, idle, config.maxWait)
+
+		for i := len(messages); i < idle; i++ {
+			<-workers
+		}
+
+		setInFlight(subject, consumerID, len(workers))
+
+		if fetchErr != nil {
+			if ctx.Err() != nil || c.drained(fetchErr) {
+				return nil
+			}
+
+			if errors.Is(fetchErr, nats.ErrBadSubscription) || errors.Is(fetchErr, nats.ErrConsumerDeleted) {
+				return fmt.Errorf("Consume: %w", fetchErr)
+			}
+
+			logging.WithAttrs(ctx, logging.StringAttr("subject", subject), logging.ErrAttr(fetchErr)).
+				Error("consume fetch error")
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(defaultConsumeRetryDelay):
+			}
+
+			continue
+		}
+
+		for _, msg := range messages {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				defer func() {
+					<-workers
+					setInFlight(subject, consumerID, len(workers))
+				}()
+
+				c.handleMsg(handlerCtx, consumerID, msg, handler)
+			}()
+		}
+	}
+}
+
+// acquireWorkers waits for an idle worker and takes up to limit idle workers.
+func acquireWorkers(ctx context.Context, workers chan struct{}, limit int) (int, bool) {
+	if ctx.Err() != nil {
+		return 0, false
+	}
+
+	select {
+	case <-ctx.Done():
+		return 0, false
+	case workers <- struct{}{}:
+	}
+
+	idle := 1
+
+	for idle < limit {
+		select {
+		case workers <- struct{}{}:
+			idle++
+		default:
+			return idle, true
+		}
+	}
+
+	return idle, true
+}
+
+// fetchBatch returns up to batch messages. Empty batch on max wait is not an error.
+func fetchBatch(ctx context.Context, sub *nats.Subscription, batch int, maxWait time.Duration) ([]*nats.Msg, error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+
+	messages, err := sub.Fetch(batch, nats.Context(fetchCtx))
+	if err != nil && ctx.Err() == nil &&
+		(errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded)) {
+		return messages, nil
+	}
+
+	return messages, err
+}
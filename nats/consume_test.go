@@ -0,0 +1,31 @@
+package mynats
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireWorkers(t *testing.T) {
+	workers := make(chan struct{}, 4)
+	workers <- struct{}{}
+
+	idle, ok := acquireWorkers(context.Background(), workers, 10)
+	require.True(t, ok)
+	require.Equal(t, 3, idle)
+	require.Len(t, workers, 4)
+
+	<-workers
+	<-workers
+
+	idle, ok = acquireWorkers(context.Background(), workers, 1)
+	require.True(t, ok)
+	require.Equal(t, 1, idle)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, ok = acquireWorkers(ctx, workers, 1)
+	require.False(t, ok)
+}
@@ -0,0 +1,173 @@
+package mynats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kazzess/libraries/logging"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	HeaderDeadLetterError          = "Dlq-Error"
+	HeaderDeadLetterSubject        = "Dlq-Original-Subject"
+	HeaderDeadLetterStream         = "Dlq-Stream"
+	HeaderDeadLetterStreamSequence = "Dlq-Stream-Sequence"
+	HeaderDeadLetterConsumer       = "Dlq-Consumer"
+	HeaderDeadLetterDelivered      = "Dlq-Delivered"
+	HeaderDeadLetterTimestamp      = "Dlq-Timestamp"
+
+	headerDeadLetterPrefix   = "Dlq-"
+	defaultReplayBatchSize   = 100
+	defaultReplayMaxWait     = time.Second
+	maxDeadLetterErrorLength = 1024
+)
+
+var ErrNoOriginalSubject = errors.New("dead letter message has no original subject")
+
+// WithDeadLetter republishes messages terminated on max deliveries or a permanent error to
+// the subject before terminating them. The subject must be bound to a stream.
+func WithDeadLetter(subject string) AckOption {
+	return func(p *AckPolicy) {
+		p.deadLetter = subject
+	}
+}
+
+// deadLetter publishes the message to the dead letter subject of the policy.
+func (c *Client) deadLetter(msg *nats.Msg, subject string, handleErr error) error {
+	metadata, err := msg.Metadata()
+	if err != nil {
+		return fmt.Errorf("message metadata: %w", err)
+	}
+
+	if _, err = c.js.PublishMsg(newDeadLetterMsg(msg, subject, metadata, handleErr)); err != nil {
+		return fmt.Errorf("publish dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// newDeadLetterMsg copies the message with headers describing the failure. The message ID is
+// derived from the stream sequence, so redeliveries of a message are dead lettered once.
+func newDeadLetterMsg(msg *nats.Msg, subject string, metadata *nats.MsgMetadata, handleErr error) *nats.Msg {
+	dlq := nats.NewMsg(subject)
+	dlq.Data = msg.Data
+
+	for key, values := range msg.Header {
+		if strings.HasPrefix(key, "Nats-") {
+			continue
+		}
+
+		dlq.Header[key] = values
+	}
+
+	errText := ""
+	if handleErr != nil {
+		errText = handleErr.Error()
+		if len(errText) > maxDeadLetterErrorLength {
+			errText = errText[:maxDeadLetterErrorLength]
+		}
+	}
+
+	dlq.Header.Set(HeaderDeadLetterError, errText)
+	dlq.Header.Set(HeaderDeadLetterSubject, msg.Subject)
+	dlq.Header.Set(HeaderDeadLetterStream, metadata.Stream)
+	dlq.Header.Set(HeaderDeadLetterStreamSequence, strconv.FormatUint(metadata.Sequence.Stream, 10))
+	dlq.Header.Set(HeaderDeadLetterConsumer, metadata.Consumer)
+	dlq.Header.Set(HeaderDeadLetterDelivered, strconv.FormatUint(metadata.NumDelivered, 10))
+	dlq.Header.Set(HeaderDeadLetterTimestamp, metadata.Timestamp.UTC().Format(time.RFC3339Nano))
+	dlq.Header.Set(nats.MsgIdHdr, "dlq-"+metadata.Stream+"-"+strconv.FormatUint(metadata.Sequence.Stream, 10))
+
+	return dlq
+}
+
+// newReplayMsg restores the original message from the dead letter message.
+func newReplayMsg(dlq *nats.Msg) (*nats.Msg, error) {
+	subject := dlq.Header.Get(HeaderDeadLetterSubject)
+	if subject == "" {
+		return nil, ErrNoOriginalSubject
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = dlq.Data
+
+	for key, values := range dlq.Header {
+		if strings.HasPrefix(key, headerDeadLetterPrefix) || strings.HasPrefix(key, "Nats-") {
+			continue
+		}
+
+		msg.Header[key] = values
+	}
+
+	return msg, nil
+}
+
+// ReplayDeadLetters republishes up to limit messages from the dead letter subject to their
+// original subjects and acks them. Non-positive limit replays all pending messages.
+// Messages without the original subject are terminated.
+func (c *Client) ReplayDeadLetters(ctx context.Context, subject, consumerID string, limit int) (int, error) {
+	if consumerID == "" {
+		consumerID = c.Config.consumerID
+	}
+
+	sub, err := c.js.PullSubscribe(subject, consumerID)
+	if err != nil {
+		return 0, fmt.Errorf("ReplayDeadLetters: %w", err)
+	}
+
+	defer func() {
+		_ = sub.Unsubscribe()
+	}()
+
+	var replayed int
+
+	for limit <= 0 || replayed < limit {
+		if ctx.Err() != nil {
+			return replayed, ctx.Err()
+		}
+
+		batch := defaultReplayBatchSize
+		if limit > 0 && limit-replayed < batch {
+			batch = limit - replayed
+		}
+
+		messages, fetchErr := sub.Fetch(batch, nats.MaxWait(defaultReplayMaxWait))
+		if errors.Is(fetchErr, nats.ErrTimeout) {
+			return replayed, nil
+		}
+
+		if fetchErr != nil {
+			return replayed, fmt.Errorf("ReplayDeadLetters: %w", fetchErr)
+		}
+
+		for _, dlq := range messages {
+			msg, replayErr := newReplayMsg(dlq)
+			if replayErr != nil {
+				logging.WithAttrs(ctx, logging.StringAttr("subject", dlq.Subject), logging.ErrAttr(replayErr)).
+					Error("replay dead letter error")
+
+				_ = dlq.Term()
+
+				continue
+			}
+
+			if _, err = c.js.PublishMsg(msg, nats.Context(ctx)); err != nil {
+				_ = dlq.Nak()
+
+				return replayed, fmt.Errorf("ReplayDeadLetters: %w", err)
+			}
+
+			if err = dlq.Ack(); err != nil {
+				return replayed, fmt.Errorf("ReplayDeadLetters: %w", err)
+			}
+
+			replayed++
+		}
+	}
+
+	return replayed, nil
+}
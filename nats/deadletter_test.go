@@ -0,0 +1,49 @@
+package mynats
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDeadLetterMsg(t *testing.T) {
+	msg := nats.NewMsg("orders.created")
+	msg.Data = []byte("payload")
+	msg.Header.Set("Traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	msg.Header.Set(nats.MsgIdHdr, "order-1")
+
+	metadata := &nats.MsgMetadata{
+		Sequence:     nats.SequencePair{Stream: 42, Consumer: 7},
+		NumDelivered: 5,
+		Stream:       "ORDERS",
+		Consumer:     "billing",
+		Timestamp:    time.Unix(1700000000, 0),
+	}
+
+	dlq := newDeadLetterMsg(msg, "DLQ.orders", metadata, errors.New("boom"))
+
+	require.Equal(t, "DLQ.orders", dlq.Subject)
+	require.Equal(t, msg.Data, dlq.Data)
+	require.Equal(t, "boom", dlq.Header.Get(HeaderDeadLetterError))
+	require.Equal(t, "orders.created", dlq.Header.Get(HeaderDeadLetterSubject))
+	require.Equal(t, "ORDERS", dlq.Header.Get(HeaderDeadLetterStream))
+	require.Equal(t, "42", dlq.Header.Get(HeaderDeadLetterStreamSequence))
+	require.Equal(t, "billing", dlq.Header.Get(HeaderDeadLetterConsumer))
+	require.Equal(t, "5", dlq.Header.Get(HeaderDeadLetterDelivered))
+	require.Equal(t, "dlq-ORDERS-42", dlq.Header.Get(nats.MsgIdHdr))
+	require.Equal(t, msg.Header.Get("Traceparent"), dlq.Header.Get("Traceparent"))
+
+	replay, err := newReplayMsg(dlq)
+	require.NoError(t, err)
+	require.Equal(t, "orders.created", replay.Subject)
+	require.Equal(t, msg.Data, replay.Data)
+	require.Equal(t, msg.Header.Get("Traceparent"), replay.Header.Get("Traceparent"))
+	require.Empty(t, replay.Header.Get(HeaderDeadLetterError))
+	require.Empty(t, replay.Header.Get(nats.MsgIdHdr))
+
+	_, err = newReplayMsg(nats.NewMsg("DLQ.orders"))
+	require.ErrorIs(t, err, ErrNoOriginalSubject)
+}
@@ -0,0 +1,213 @@
+package mynats
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/Kazzess/libraries/logging"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	defaultDedupTTL      = 24 * time.Hour
+	defaultDedupClaimTTL = 30 * time.Second
+)
+
+// ErrMessageInProgress is returned for a message whose ID is claimed by another handler,
+// so the message is redelivered instead of being acked. It isn't counted as a failed
+// delivery by the ack policy, so the message isn't terminated or dead-lettered.
+var ErrMessageInProgress = errors.New("message is in progress")
+
+// DedupStore records IDs of messages in progress and handled messages.
+// The redis and postgresql modules provide shared stores.
+type DedupStore interface {
+	// Claim records the ID as in progress for the ttl and reports true if it isn't
+	// recorded or expired. Otherwise it reports false and whether the message is handled.
+	Claim(ctx context.Context, id string, ttl time.Duration) (claimed, done bool, err error)
+	// Complete records the claimed ID as handled for the ttl.
+	Complete(ctx context.Context, id string, ttl time.Duration) error
+	// Remove forgets the ID, so the failed message is handled on redelivery.
+	Remove(ctx context.Context, id string) error
+}
+
+type IdempotencyConfig struct {
+	ttl       time.Duration
+	claimTTL  time.Duration
+	extractor func(msg *nats.Msg) string
+}
+
+type IdempotencyOption func(*IdempotencyConfig)
+
+// WithIdempotencyTTL sets how long handled IDs are kept, a day by default.
+// It should exceed the redelivery period and the publisher retries.
+func WithIdempotencyTTL(ttl time.Duration) IdempotencyOption {
+	return func(c *IdempotencyConfig) {
+		c.ttl = ttl
+	}
+}
+
+// WithIdempotencyClaimTTL sets how long the ID of a message in progress is kept,
+// 30 seconds by default. It should exceed the handler duration, the message of a crashed
+// handler is handled again on redelivery after the claim expires.
+func WithIdempotencyClaimTTL(ttl time.Duration) IdempotencyOption {
+	return func(c *IdempotencyConfig) {
+		c.claimTTL = ttl
+	}
+}
+
+// WithIdempotencyHeader takes the message ID from the header, Nats-Msg-Id by default.
+func WithIdempotencyHeader(header string) IdempotencyOption {
+	return func(c *IdempotencyConfig) {
+		c.extractor = func(msg *nats.Msg) string {
+			return msg.Header.Get(header)
+		}
+	}
+}
+
+// WithIdempotencyKey sets the message ID extractor, e.g. taking the ID from the payload.
+func WithIdempotencyKey(extractor func(msg *nats.Msg) string) IdempotencyOption {
+	return func(c *IdempotencyConfig) {
+		c.extractor = extractor
+	}
+}
+
+func newIdempotencyConfig(options []IdempotencyOption) *IdempotencyConfig {
+	config := &IdempotencyConfig{
+		ttl:      defaultDedupTTL,
+		claimTTL: defaultDedupClaimTTL,
+		extractor: func(msg *nats.Msg) string {
+			return msg.Header.Get(nats.MsgIdHdr)
+		},
+	}
+
+	for _, o := range options {
+		o(config)
+	}
+
+	return config
+}
+
+// Idempotent skips messages whose ID is recorded in the store, so they are acked without
+// calling the handler. The ID is claimed before the handler and recorded as handled after
+// it succeeds, or removed if it fails. A message whose ID is claimed by another handler
+// fails with ErrMessageInProgress, so it is redelivered.
+// IDs are scoped by the stream and consumer, so each consumer handles the message once.
+// Messages without ID are always handled.
+func Idempotent(store DedupStore, handler SubscribeHandler, options ...IdempotencyOption) SubscribeHandler {
+	config := newIdempotencyConfig(options)
+
+	return func(ctx context.Context, msg *nats.Msg) error {
+		return handleOnce(ctx, store, config, msg, func() error {
+			return handler(ctx, msg)
+		})
+	}
+}
+
+// IdempotentHandler is Idempotent for Subscribe handlers.
+func IdempotentHandler[T any](store DedupStore, handler Handler[T], options ...IdempotencyOption) Handler[T] {
+	config := newIdempotencyConfig(options)
+
+	return func(ctx context.Context, v T, meta Meta) error {
+		return handleOnce(ctx, store, config, meta.Msg, func() error {
+			return handler(ctx, v, meta)
+		})
+	}
+}
+
+func handleOnce(ctx context.Context, store DedupStore, config *IdempotencyConfig, msg *nats.Msg, handle func() error) error {
+	id := config.extractor(msg)
+	if id == "" {
+		return handle()
+	}
+
+	key := dedupKey(msg, id)
+
+	claimed, done, err := store.Claim(ctx, key, config.claimTTL)
+	if err != nil {
+		return err
+	}
+
+	if !claimed {
+		if !done {
+			return ErrMessageInProgress
+		}
+
+		observeDuplicate(msg.Subject)
+
+		logging.WithAttrs(ctx, logging.StringAttr("subject", msg.Subject), logging.StringAttr("id", id)).
+			Info("skip duplicate message")
+
+		return nil
+	}
+
+	if err = handle(); err != nil {
+		if removeErr := store.Remove(context.WithoutCancel(ctx), key); removeErr != nil {
+			logging.WithAttrs(ctx, logging.StringAttr("id", id), logging.ErrAttr(removeErr)).
+				Error("remove message id error")
+		}
+
+		return err
+	}
+
+	// The message is handled, so it is acked even if the ID isn't recorded.
+	if err = store.Complete(context.WithoutCancel(ctx), key, config.ttl); err != nil {
+		logging.WithAttrs(ctx, logging.StringAttr("id", id), logging.ErrAttr(err)).
+			Error("complete message id error")
+	}
+
+	return nil
+}
+
+// dedupKey scopes the ID by the stream and consumer of the message.
+func dedupKey(msg *nats.Msg, id string) string {
+	metadata, err := msg.Metadata()
+	if err != nil {
+		return msg.Subject + ":" + id
+	}
+
+	return metadata.Stream + "." + metadata.Consumer + ":" + id
+}
+
+type msgIDKey struct{}
+
+// ContextWithMsgID sets Nats-Msg-Id of messages published with the context,
+// so JetStream drops duplicates published within the stream duplicates window.
+// The nats.MsgId publish option takes precedence.
+func ContextWithMsgID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, msgIDKey{}, id)
+}
+
+// WithContentMsgID sets Nats-Msg-Id of published messages without ID to the hash
+// of the subject and data, so identical messages are deduplicated by JetStream.
+func WithContentMsgID(enabled bool) OptionSetter {
+	return func(c *Config) { c.hashMsgID = enabled }
+}
+
+// setMsgID sets Nats-Msg-Id from the context or the content hash if it isn't set.
+func (c *Client) setMsgID(ctx context.Context, msg *nats.Msg) {
+	if msg.Header.Get(nats.MsgIdHdr) != "" {
+		return
+	}
+
+	if id, ok := ctx.Value(msgIDKey{}).(string); ok && id != "" {
+		msg.Header.Set(nats.MsgIdHdr, id)
+
+		return
+	}
+
+	if c.Config.hashMsgID {
+		msg.Header.Set(nats.MsgIdHdr, contentMsgID(msg.Subject, msg.Data))
+	}
+}
+
+func contentMsgID(subject string, data []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(subject))
+	hash.Write([]byte{0})
+	hash.Write(data)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
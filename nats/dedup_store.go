@@ -0,0 +1,108 @@
+package mynats
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const defaultDedupCapacity = 100000
+
+// MemoryDedupStore keeps IDs in the process memory, so duplicates are detected only
+// by the same instance. The least recently added IDs are evicted over the capacity.
+type MemoryDedupStore struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+type memoryDedupItem struct {
+	id        string
+	done      bool
+	expiresAt time.Time
+}
+
+// NewMemoryDedupStore returns the LRU store, non-positive capacity means 100000 IDs.
+func NewMemoryDedupStore(capacity int) *MemoryDedupStore {
+	if capacity <= 0 {
+		capacity = defaultDedupCapacity
+	}
+
+	return &MemoryDedupStore{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryDedupStore) Claim(_ context.Context, id string, ttl time.Duration) (bool, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	if elem, ok := s.items[id]; ok {
+		item := elem.Value.(*memoryDedupItem)
+		if now.Before(item.expiresAt) {
+			return false, item.done, nil
+		}
+
+		item.done = false
+		item.expiresAt = now.Add(ttl)
+		s.order.MoveToFront(elem)
+
+		return true, false, nil
+	}
+
+	s.push(&memoryDedupItem{id: id, expiresAt: now.Add(ttl)})
+
+	return true, false, nil
+}
+
+func (s *MemoryDedupStore) Complete(_ context.Context, id string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt := s.now().Add(ttl)
+
+	// The claim may be evicted while the message is handled.
+	elem, ok := s.items[id]
+	if !ok {
+		s.push(&memoryDedupItem{id: id, done: true, expiresAt: expiresAt})
+
+		return nil
+	}
+
+	item := elem.Value.(*memoryDedupItem)
+	item.done = true
+	item.expiresAt = expiresAt
+
+	return nil
+}
+
+func (s *MemoryDedupStore) Remove(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[id]; ok {
+		s.order.Remove(elem)
+		delete(s.items, id)
+	}
+
+	return nil
+}
+
+// push adds the item and evicts the oldest items over the capacity.
+func (s *MemoryDedupStore) push(item *memoryDedupItem) {
+	s.items[item.id] = s.order.PushFront(item)
+
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*memoryDedupItem).id)
+	}
+}
@@ -0,0 +1,159 @@
+package mynats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryDedupStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	store := NewMemoryDedupStore(2)
+	store.now = func() time.Time { return now }
+
+	claimed, done, err := store.Claim(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	require.False(t, done)
+
+	claimed, done, _ = store.Claim(ctx, "a", time.Minute)
+	require.False(t, claimed)
+	require.False(t, done, "claimed ID is in progress")
+
+	require.NoError(t, store.Complete(ctx, "a", time.Hour))
+
+	now = now.Add(2 * time.Minute)
+
+	claimed, done, _ = store.Claim(ctx, "a", time.Minute)
+	require.False(t, claimed)
+	require.True(t, done, "completed ID is kept for its ttl")
+
+	now = now.Add(time.Hour)
+
+	claimed, _, _ = store.Claim(ctx, "a", time.Minute)
+	require.True(t, claimed, "expired ID is claimed again")
+
+	_, _, _ = store.Claim(ctx, "b", time.Minute)
+	_, _, _ = store.Claim(ctx, "c", time.Minute)
+
+	claimed, _, _ = store.Claim(ctx, "a", time.Minute)
+	require.True(t, claimed, "the oldest ID is evicted over the capacity")
+
+	require.NoError(t, store.Remove(ctx, "a"))
+
+	claimed, _, _ = store.Claim(ctx, "a", time.Minute)
+	require.True(t, claimed)
+}
+
+func TestIdempotent(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDedupStore(0)
+
+	var calls int
+
+	failing := true
+
+	handler := Idempotent(store, func(ctx context.Context, msg *nats.Msg) error {
+		calls++
+
+		if failing {
+			return errors.New("temporary failure")
+		}
+
+		return nil
+	})
+
+	msg := nats.NewMsg("orders.created")
+	msg.Header.Set(nats.MsgIdHdr, "order-1")
+
+	require.Error(t, handler(ctx, msg))
+
+	failing = false
+
+	require.NoError(t, handler(ctx, msg), "failed message is handled again")
+	require.NoError(t, handler(ctx, msg), "duplicate is skipped")
+	require.Equal(t, 2, calls)
+
+	require.NoError(t, handler(ctx, nats.NewMsg("orders.created")))
+	require.NoError(t, handler(ctx, nats.NewMsg("orders.created")))
+	require.Equal(t, 4, calls, "messages without ID are always handled")
+}
+
+func TestIdempotent_Redelivery(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	store := NewMemoryDedupStore(0)
+	store.now = func() time.Time { return now }
+
+	var (
+		calls    int
+		redeliver func() error
+	)
+
+	msg := nats.NewMsg("orders.created")
+	msg.Header.Set(nats.MsgIdHdr, "order-1")
+
+	handler := Idempotent(store, func(ctx context.Context, msg *nats.Msg) error {
+		calls++
+
+		if redeliver != nil {
+			// The message is redelivered while the handler is in progress, e.g. after AckWait.
+			require.ErrorIs(t, redeliver(), ErrMessageInProgress)
+
+			return errors.New("failed partway")
+		}
+
+		return nil
+	})
+
+	redeliver = func() error { return handler(ctx, msg) }
+
+	require.Error(t, handler(ctx, msg))
+
+	redeliver = nil
+
+	require.NoError(t, handler(ctx, msg), "failed message is handled on redelivery")
+	require.NoError(t, handler(ctx, msg), "duplicate is skipped")
+	require.Equal(t, 2, calls)
+
+	// The handler crashes without releasing the claim.
+	crashed := nats.NewMsg("orders.created")
+	crashed.Header.Set(nats.MsgIdHdr, "order-2")
+
+	_, _, err := store.Claim(ctx, dedupKey(crashed, "order-2"), defaultDedupClaimTTL)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, handler(ctx, crashed), ErrMessageInProgress)
+
+	now = now.Add(defaultDedupClaimTTL)
+
+	require.NoError(t, handler(ctx, crashed), "message is handled after the claim expires")
+	require.Equal(t, 3, calls)
+}
+
+func TestClient_SetMsgID(t *testing.T) {
+	client := &Client{Config: NewConfig(nil, "", WithContentMsgID(true))}
+
+	msg := nats.NewMsg("orders.created")
+	msg.Data = []byte("payload")
+	client.setMsgID(context.Background(), msg)
+	require.Equal(t, contentMsgID("orders.created", []byte("payload")), msg.Header.Get(nats.MsgIdHdr))
+	require.NotEqual(t, contentMsgID("orders.updated", []byte("payload")), msg.Header.Get(nats.MsgIdHdr))
+
+	msg = nats.NewMsg("orders.created")
+	client.setMsgID(ContextWithMsgID(context.Background(), "order-1"), msg)
+	require.Equal(t, "order-1", msg.Header.Get(nats.MsgIdHdr))
+
+	client = &Client{Config: NewConfig(nil, "")}
+
+	msg = nats.NewMsg("orders.created")
+	client.setMsgID(context.Background(), msg)
+	require.Empty(t, msg.Header.Get(nats.MsgIdHdr))
+}
@@ -0,0 +1,287 @@
+package mynats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Kazzess/libraries/logging"
+	"github.com/Kazzess/libraries/tracing"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const storeKV = "kv"
+
+type (
+	KeyValueEntry = nats.KeyValueEntry
+	KeyValueOp    = nats.KeyValueOp
+)
+
+const (
+	KeyValuePut    = nats.KeyValuePut
+	KeyValueDelete = nats.KeyValueDelete
+	KeyValuePurge  = nats.KeyValuePurge
+)
+
+var (
+	ErrKeyNotFound = nats.ErrKeyNotFound
+	ErrKeyExists   = nats.ErrKeyExists
+	// ErrRevisionMismatch is returned by Update when the key was changed after the revision.
+	ErrRevisionMismatch = errors.New("key revision mismatch")
+)
+
+type KeyValueOption func(config *nats.KeyValueConfig)
+
+func WithKeyValueDescription(desc string) KeyValueOption {
+	return func(c *nats.KeyValueConfig) {
+		c.Description = desc
+	}
+}
+
+// WithKeyValueTTL sets how long values are kept. Zero keeps them forever.
+func WithKeyValueTTL(ttl time.Duration) KeyValueOption {
+	return func(c *nats.KeyValueConfig) {
+		c.TTL = ttl
+	}
+}
+
+// WithKeyValueHistory sets the number of revisions kept per key, 1 by default and 64 at most.
+func WithKeyValueHistory(history uint8) KeyValueOption {
+	return func(c *nats.KeyValueConfig) {
+		c.History = history
+	}
+}
+
+func WithKeyValueMaxBytes(maxBytes int64) KeyValueOption {
+	return func(c *nats.KeyValueConfig) {
+		c.MaxBytes = maxBytes
+	}
+}
+
+func WithKeyValueMaxValueSize(maxValueSize int32) KeyValueOption {
+	return func(c *nats.KeyValueConfig) {
+		c.MaxValueSize = maxValueSize
+	}
+}
+
+func WithKeyValueStorage(storage nats.StorageType) KeyValueOption {
+	return func(c *nats.KeyValueConfig) {
+		c.Storage = storage
+	}
+}
+
+func WithKeyValueReplicas(replicas int) KeyValueOption {
+	return func(c *nats.KeyValueConfig) {
+		c.Replicas = replicas
+	}
+}
+
+// KeyValue is a JetStream key-value bucket.
+type KeyValue struct {
+	client *Client
+	kv     nats.KeyValue
+	bucket string
+}
+
+// KeyValue binds the bucket, creating it with the options if it doesn't exist.
+// Options of the existing bucket are not changed.
+func (c *Client) KeyValue(bucket string, options ...KeyValueOption) (*KeyValue, error) {
+	kv, err := c.js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		config := &nats.KeyValueConfig{
+			Bucket: bucket,
+		}
+
+		for _, option := range options {
+			option(config)
+		}
+
+		kv, err = c.js.CreateKeyValue(config)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("KeyValue: %w", err)
+	}
+
+	return &KeyValue{client: c, kv: kv, bucket: bucket}, nil
+}
+
+func (kv *KeyValue) Bucket() string {
+	return kv.bucket
+}
+
+// Get returns the last revision of the key or ErrKeyNotFound.
+func (kv *KeyValue) Get(ctx context.Context, key string) (_ KeyValueEntry, err error) {
+	defer kv.client.observeStore(ctx, storeKV, kv.bucket, "get", key)(&err)
+
+	entry, err := kv.kv.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("KeyValue.Get: %w", err)
+	}
+
+	return entry, nil
+}
+
+// Put sets the value and returns its revision.
+func (kv *KeyValue) Put(ctx context.Context, key string, value []byte) (_ uint64, err error) {
+	defer kv.client.observeStore(ctx, storeKV, kv.bucket, "put", key)(&err)
+
+	revision, err := kv.kv.Put(key, value)
+	if err != nil {
+		return 0, fmt.Errorf("KeyValue.Put: %w", err)
+	}
+
+	return revision, nil
+}
+
+// Create sets the value only if the key doesn't exist, else returns ErrKeyExists.
+func (kv *KeyValue) Create(ctx context.Context, key string, value []byte) (_ uint64, err error) {
+	defer kv.client.observeStore(ctx, storeKV, kv.bucket, "create", key)(&err)
+
+	revision, err := kv.kv.Create(key, value)
+	if err != nil {
+		return 0, fmt.Errorf("KeyValue.Create: %w", err)
+	}
+
+	return revision, nil
+}
+
+// Update sets the value only if the last revision of the key is revision,
+// else returns ErrRevisionMismatch.
+func (kv *KeyValue) Update(ctx context.Context, key string, value []byte, revision uint64) (_ uint64, err error) {
+	defer kv.client.observeStore(ctx, storeKV, kv.bucket, "update", key)(&err)
+
+	newRevision, err := kv.kv.Update(key, value, revision)
+	if err != nil {
+		var apiErr *nats.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode == nats.JSErrCodeStreamWrongLastSequence {
+			return 0, fmt.Errorf("KeyValue.Update: %w: %w", ErrRevisionMismatch, err)
+		}
+
+		return 0, fmt.Errorf("KeyValue.Update: %w", err)
+	}
+
+	return newRevision, nil
+}
+
+// Delete marks the key deleted, the history is kept.
+func (kv *KeyValue) Delete(ctx context.Context, key string) (err error) {
+	defer kv.client.observeStore(ctx, storeKV, kv.bucket, "delete", key)(&err)
+
+	if err = kv.kv.Delete(key); err != nil {
+		return fmt.Errorf("KeyValue.Delete: %w", err)
+	}
+
+	return nil
+}
+
+// Keys returns the keys which are not deleted.
+func (kv *KeyValue) Keys(ctx context.Context) (_ []string, err error) {
+	defer kv.client.observeStore(ctx, storeKV, kv.bucket, "keys", "")(&err)
+
+	keys, err := kv.kv.Keys(nats.Context(ctx))
+	if errors.Is(err, nats.ErrNoKeysFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("KeyValue.Keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// KeyValueUpdate is the change passed to the WatchKeyValue handler. Value is zero
+// for deleted and purged keys.
+type KeyValueUpdate[T any] struct {
+	Key       string
+	Value     T
+	Revision  uint64
+	Operation KeyValueOp
+	Created   time.Time
+}
+
+type KeyValueHandler[T any] func(ctx context.Context, update KeyValueUpdate[T]) error
+
+// WatchKeyValue passes the current values and the following changes of the keys matching
+// the pattern to the handler until ctx is done. Values are decoded with the client codec.
+// Handler and decode errors are logged and the watch continues.
+func WatchKeyValue[T any](ctx context.Context, kv *KeyValue, pattern string, handler KeyValueHandler[T]) error {
+	watcher, err := kv.kv.Watch(pattern, nats.Context(ctx))
+	if err != nil {
+		return fmt.Errorf("WatchKeyValue: %w", err)
+	}
+
+	defer func() {
+		_ = watcher.Stop()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry, ok := <-watcher.Updates():
+			if !ok {
+				return nil
+			}
+
+			// nil marks the end of the current values.
+			if entry == nil {
+				continue
+			}
+
+			update := KeyValueUpdate[T]{
+				Key:       entry.Key(),
+				Revision:  entry.Revision(),
+				Operation: entry.Operation(),
+				Created:   entry.Created(),
+			}
+
+			if entry.Operation() == KeyValuePut {
+				update.Value, err = decode[T](kv.client.Config.codec, entry.Value())
+				if err != nil {
+					logging.WithAttrs(ctx, logging.StringAttr("key", entry.Key()), logging.ErrAttr(err)).
+						Error("decode key value error")
+
+					continue
+				}
+			}
+
+			if err = handler(ctx, update); err != nil {
+				logging.WithAttrs(ctx, logging.StringAttr("key", entry.Key()), logging.ErrAttr(err)).
+					Error("handle key value update error")
+			}
+		}
+	}
+}
+
+// observeStore starts the span of the store operation and returns the func
+// observing its duration.
+func (c *Client) observeStore(ctx context.Context, store, bucket, operation, key string) ObserveWithErr {
+	start := time.Now()
+
+	var span trace.Span
+	if c.Config.tracing {
+		ctx, span = tracing.Start(ctx, store+"."+operation)
+
+		tracing.TraceValue(ctx, "bucket", bucket)
+
+		if key != "" {
+			tracing.TraceValue(ctx, "key", key)
+		}
+	}
+
+	return func(err *error) {
+		if span != nil {
+			if *err != nil {
+				tracing.Error(ctx, *err)
+			}
+
+			span.End()
+		}
+
+		observeStoreOperation(store, bucket, operation, start, *err)
+	}
+}
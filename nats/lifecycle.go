@@ -0,0 +1,121 @@
+package mynats
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	connectionEventDisconnected = "disconnected"
+	connectionEventReconnected  = "reconnected"
+	connectionEventClosed       = "closed"
+	connectionEventSlowConsumer = "slow_consumer"
+	connectionEventAsyncError   = "async_error"
+)
+
+// Drain stops the subscriptions created by the client from receiving new messages and
+// waits until the pending messages are handled or ctx is done. The connection stays open,
+// so messages may be published after Drain. Consume returns after its handlers in flight.
+// Durable consumers of SubscribeAsync, SubscribeSync and Consume are kept. NATS deletes
+// consumers created by PullSubscribe, bind it to a consumer created with CreateOrUpdateConsumer.
+// Messages which arrive after the subscription is stopped are redelivered after AckWait.
+func (c *Client) Drain(ctx context.Context) error {
+	c.draining.Store(true)
+
+	var result error
+
+	subs := c.subscriptions()
+
+	for _, sub := range subs {
+		if err := stopSubscription(sub); err != nil && !errors.Is(err, nats.ErrBadSubscription) {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	for _, sub := range subs {
+		select {
+		case <-sub.StatusChanged(nats.SubscriptionClosed):
+			c.untrack(sub)
+		case <-ctx.Done():
+			return multierror.Append(result, ctx.Err())
+		}
+	}
+
+	return result
+}
+
+// stopSubscription stops the subscription after the messages it received are handled.
+// Drain of the subscription deletes the consumer created by it, so push subscriptions
+// are stopped by the number of received messages instead, which keeps the consumer.
+func stopSubscription(sub *nats.Subscription) error {
+	if sub.Type() == nats.PullSubscription {
+		return sub.Drain()
+	}
+
+	// Delivered is read first, so a message handled in between isn't counted twice.
+	delivered, err := sub.Delivered()
+	if err != nil {
+		return err
+	}
+
+	pending, _, err := sub.Pending()
+	if err != nil {
+		return err
+	}
+
+	// Pending of async subscriptions includes the message in the handler. A lower limit only
+	// leaves the last message to redelivery, a higher one would wait for a message forever.
+	if sub.Type() == nats.AsyncSubscription && pending > 0 {
+		pending--
+	}
+
+	return sub.AutoUnsubscribe(int(delivered) + pending)
+}
+
+// track remembers the subscription for Drain.
+func (c *Client) track(sub *nats.Subscription) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.subs == nil {
+		c.subs = make(map[*nats.Subscription]struct{})
+	}
+
+	// Forget subscriptions closed by the caller.
+	for s := range c.subs {
+		if !s.IsValid() {
+			delete(c.subs, s)
+		}
+	}
+
+	c.subs[sub] = struct{}{}
+}
+
+func (c *Client) untrack(sub *nats.Subscription) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.subs, sub)
+}
+
+func (c *Client) subscriptions() []*nats.Subscription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	subs := make([]*nats.Subscription, 0, len(c.subs))
+	for sub := range c.subs {
+		subs = append(subs, sub)
+	}
+
+	return subs
+}
+
+// drained reports whether err is caused by Drain or Close of the subscription.
+func (c *Client) drained(err error) bool {
+	return c.draining.Load() &&
+		(errors.Is(err, nats.ErrBadSubscription) || errors.Is(err, nats.ErrConnectionClosed) ||
+			errors.Is(err, nats.ErrConnectionDraining))
+}
@@ -48,6 +48,86 @@ var (
 		},
 	)
 
+	ackTotal = metrics.NewCounterVec(
+		metrics.CounterOpts{
+			Name: "nats_stream_ack_total",
+			Help: "The number of messages settled by the consumer ack policy",
+		},
+		[]string{
+			"subject",
+			"consumer_id",
+			"action",
+		},
+	)
+
+	inFlight = metrics.NewGaugeVec(
+		metrics.GaugeOpts{
+			Name: "nats_stream_in_flight",
+			Help: "The number of messages handled by the pull consumer workers",
+		},
+		[]string{
+			"subject",
+			"consumer_id",
+		},
+	)
+
+	// requestDurationMs is a histogram that measures the time from sending a request till its reply (milliseconds).
+	requestDurationMs = metrics.NewHistogramVec(
+		metrics.HistogramOpts{
+			Name:    "nats_request_duration_ms",
+			Help:    "The time from sending a request till its reply (milliseconds)",
+			Buckets: processMessageBuckets,
+		},
+		[]string{
+			"subject",
+			"is_err",
+		},
+	)
+
+	// responseProcessingTimeMs is a histogram that measures the time that service handles a request (milliseconds).
+	responseProcessingTimeMs = metrics.NewHistogramVec(
+		metrics.HistogramOpts{
+			Name:    "nats_service_request_processing_time_ms",
+			Help:    "The time that service handles a request (milliseconds)",
+			Buckets: processMessageBuckets,
+		},
+		[]string{
+			"subject",
+			"is_err",
+		},
+	)
+
+	// storeOperationDurationMs is a histogram that measures the time of key-value and object store operations (milliseconds).
+	storeOperationDurationMs = metrics.NewHistogramVec(
+		metrics.HistogramOpts{
+			Name:    "nats_store_operation_duration_ms",
+			Help:    "The time of key-value and object store operations (milliseconds)",
+			Buckets: processMessageBuckets,
+		},
+		[]string{
+			"store",
+			"bucket",
+			"operation",
+			"is_err",
+		},
+	)
+
+	duplicateTotal = metrics.NewCounterVec(
+		metrics.CounterOpts{
+			Name: "nats_stream_duplicate_messages_total",
+			Help: "The number of duplicate messages skipped by idempotent handlers",
+		},
+		[]string{"subject"},
+	)
+
+	connectionEventsTotal = metrics.NewCounterVec(
+		metrics.CounterOpts{
+			Name: "nats_connection_events_total",
+			Help: "The number of NATS connection events: disconnects, reconnects, slow consumers and async errors",
+		},
+		[]string{"endpoint", "consumer_id", "event"},
+	)
+
 	// natsAvailability is a gauge that indicates the availability of NATS connection
 	//(1 for connected, 0 for disconnected).
 	natsAvailability = metrics.NewGaugeVec(
@@ -86,6 +166,33 @@ func ObserveProcessingTimeMs(subject, consumerID string, isAsync bool) ObserveWi
 	}
 }
 
+func observeAck(subject, consumerID string, action AckAction) {
+	ackTotal.WithLabelValues(subject, consumerID, string(action)).Inc()
+}
+
+func setInFlight(subject, consumerID string, n int) {
+	inFlight.WithLabelValues(subject, consumerID).Set(float64(n))
+}
+
+func observeRequest(subject string, start time.Time, err *error) {
+	requestDurationMs.WithLabelValues(subject, strconv.FormatBool(*err != nil)).
+		Observe(float64(time.Since(start).Milliseconds()))
+}
+
+func observeResponse(subject string, start time.Time, err error) {
+	responseProcessingTimeMs.WithLabelValues(subject, strconv.FormatBool(err != nil)).
+		Observe(float64(time.Since(start).Milliseconds()))
+}
+
+func observeStoreOperation(store, bucket, operation string, start time.Time, err error) {
+	storeOperationDurationMs.WithLabelValues(store, bucket, operation, strconv.FormatBool(err != nil)).
+		Observe(float64(time.Since(start).Milliseconds()))
+}
+
+func observeDuplicate(subject string) {
+	duplicateTotal.WithLabelValues(subject).Inc()
+}
+
 // metricsMetadata returns the timestamp and consumer ID from the message metadata.
 func metricsMetadata(msg *Msg) (time.Time, string) {
 	metadata, err := msg.Metadata()
@@ -96,6 +203,24 @@ func metricsMetadata(msg *Msg) (time.Time, string) {
 	return metadata.Timestamp, metadata.Consumer
 }
 
+func observeConnectionEvent(cfg *Config, event string) {
+	connectionEventsTotal.WithLabelValues(strings.Join(cfg.servers, ","), cfg.consumerID, event).Inc()
+}
+
+// setAvailability reports the connection state to the availability gauge and the health checker.
+func setAvailability(cfg *Config, available bool) {
+	var value float64
+	if available {
+		value = 1
+	}
+
+	natsAvailability.WithLabelValues(strings.Join(cfg.servers, ","), cfg.consumerID).Set(value)
+
+	if cfg.health.checker != nil {
+		cfg.health.checker.SetStatus(cfg.health.name, available)
+	}
+}
+
 // MonitorNatsAvailability monitors the availability of NATS connection.
 func checkNatsAvailability(ctx context.Context, client *nats.Conn, cfg *Config) {
 	dsn := strings.Join(cfg.servers, ",")
@@ -109,19 +234,7 @@ func checkNatsAvailability(ctx context.Context, client *nats.Conn, cfg *Config)
 		for {
 			select {
 			case <-ticker.C:
-				if client.IsConnected() {
-					natsAvailability.WithLabelValues(dsn, cfg.consumerID).Set(1)
-
-					if cfg.health.checker != nil {
-						cfg.health.checker.SetStatus(cfg.health.name, true)
-					}
-				} else {
-					natsAvailability.WithLabelValues(dsn, cfg.consumerID).Set(0)
-
-					if cfg.health.checker != nil {
-						cfg.health.checker.SetStatus(cfg.health.name, false)
-					}
-				}
+				setAvailability(cfg, client.IsConnected())
 			case <-ctx.Done():
 				return
 			}
@@ -0,0 +1,168 @@
+package mynats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const storeObject = "object_store"
+
+type (
+	ObjectMeta   = nats.ObjectMeta
+	ObjectInfo   = nats.ObjectInfo
+	ObjectResult = nats.ObjectResult
+)
+
+var ErrObjectNotFound = nats.ErrObjectNotFound
+
+type ObjectStoreOption func(config *nats.ObjectStoreConfig)
+
+func WithObjectStoreDescription(desc string) ObjectStoreOption {
+	return func(c *nats.ObjectStoreConfig) {
+		c.Description = desc
+	}
+}
+
+// WithObjectStoreTTL sets how long objects are kept. Zero keeps them forever.
+func WithObjectStoreTTL(ttl time.Duration) ObjectStoreOption {
+	return func(c *nats.ObjectStoreConfig) {
+		c.TTL = ttl
+	}
+}
+
+func WithObjectStoreMaxBytes(maxBytes int64) ObjectStoreOption {
+	return func(c *nats.ObjectStoreConfig) {
+		c.MaxBytes = maxBytes
+	}
+}
+
+func WithObjectStoreStorage(storage nats.StorageType) ObjectStoreOption {
+	return func(c *nats.ObjectStoreConfig) {
+		c.Storage = storage
+	}
+}
+
+func WithObjectStoreReplicas(replicas int) ObjectStoreOption {
+	return func(c *nats.ObjectStoreConfig) {
+		c.Replicas = replicas
+	}
+}
+
+func WithObjectStoreMetadata(metadata map[string]string) ObjectStoreOption {
+	return func(c *nats.ObjectStoreConfig) {
+		c.Metadata = metadata
+	}
+}
+
+// ObjectStore is a JetStream object store bucket. Objects are stored in chunks,
+// so they may exceed the max payload size.
+type ObjectStore struct {
+	client *Client
+	os     nats.ObjectStore
+	bucket string
+}
+
+// ObjectStore binds the bucket, creating it with the options if it doesn't exist.
+// Options of the existing bucket are not changed.
+func (c *Client) ObjectStore(bucket string, options ...ObjectStoreOption) (*ObjectStore, error) {
+	os, err := c.js.ObjectStore(bucket)
+	if errors.Is(err, nats.ErrStreamNotFound) || errors.Is(err, nats.ErrBucketNotFound) {
+		config := &nats.ObjectStoreConfig{
+			Bucket: bucket,
+		}
+
+		for _, option := range options {
+			option(config)
+		}
+
+		os, err = c.js.CreateObjectStore(config)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("ObjectStore: %w", err)
+	}
+
+	return &ObjectStore{client: c, os: os, bucket: bucket}, nil
+}
+
+func (s *ObjectStore) Bucket() string {
+	return s.bucket
+}
+
+// Put reads r till EOF and stores it under meta.Name, replacing the previous object.
+func (s *ObjectStore) Put(ctx context.Context, meta ObjectMeta, r io.Reader) (_ *ObjectInfo, err error) {
+	defer s.client.observeStore(ctx, storeObject, s.bucket, "put", meta.Name)(&err)
+
+	info, err := s.os.Put(&meta, r, nats.Context(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("ObjectStore.Put: %w", err)
+	}
+
+	return info, nil
+}
+
+// Get returns the object reader, which must be closed. The digest is verified on EOF.
+func (s *ObjectStore) Get(ctx context.Context, name string) (_ ObjectResult, err error) {
+	defer s.client.observeStore(ctx, storeObject, s.bucket, "get", name)(&err)
+
+	result, err := s.os.Get(name, nats.Context(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("ObjectStore.Get: %w", err)
+	}
+
+	return result, nil
+}
+
+// Info returns the object metadata or ErrObjectNotFound.
+func (s *ObjectStore) Info(ctx context.Context, name string) (_ *ObjectInfo, err error) {
+	defer s.client.observeStore(ctx, storeObject, s.bucket, "info", name)(&err)
+
+	info, err := s.os.GetInfo(name, nats.Context(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("ObjectStore.Info: %w", err)
+	}
+
+	return info, nil
+}
+
+// UpdateMeta changes the object metadata. Changing meta.Name renames the object.
+func (s *ObjectStore) UpdateMeta(ctx context.Context, name string, meta ObjectMeta) (err error) {
+	defer s.client.observeStore(ctx, storeObject, s.bucket, "update_meta", name)(&err)
+
+	if err = s.os.UpdateMeta(name, &meta); err != nil {
+		return fmt.Errorf("ObjectStore.UpdateMeta: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the objects which are not deleted.
+func (s *ObjectStore) List(ctx context.Context) (_ []*ObjectInfo, err error) {
+	defer s.client.observeStore(ctx, storeObject, s.bucket, "list", "")(&err)
+
+	objects, err := s.os.List(nats.Context(ctx))
+	if errors.Is(err, nats.ErrNoObjectsFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("ObjectStore.List: %w", err)
+	}
+
+	return objects, nil
+}
+
+func (s *ObjectStore) Delete(ctx context.Context, name string) (err error) {
+	defer s.client.observeStore(ctx, storeObject, s.bucket, "delete", name)(&err)
+
+	if err = s.os.Delete(name); err != nil {
+		return fmt.Errorf("ObjectStore.Delete: %w", err)
+	}
+
+	return nil
+}
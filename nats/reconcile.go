@@ -0,0 +1,86 @@
+package mynats
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ConfigChange is a field changed by reconciliation.
+type ConfigChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+// ConfigDiff reports what UpdateStream or CreateOrUpdateConsumer changed.
+type ConfigDiff struct {
+	Name    string
+	Created bool
+	Changes []ConfigChange
+}
+
+// Changed reports whether the stream or consumer was created or updated.
+func (d ConfigDiff) Changed() bool {
+	return d.Created || len(d.Changes) > 0
+}
+
+func (d ConfigDiff) String() string {
+	switch {
+	case d.Created:
+		return d.Name + ": created"
+	case len(d.Changes) == 0:
+		return d.Name + ": up to date"
+	}
+
+	changes := make([]string, 0, len(d.Changes))
+	for _, change := range d.Changes {
+		changes = append(changes, fmt.Sprintf("%s %s -> %s", change.Field, change.From, change.To))
+	}
+
+	return d.Name + ": " + strings.Join(changes, ", ")
+}
+
+// mergeConfig sets the non-zero fields of desired into current and returns the changed
+// fields. Zero fields are left to the server defaults, so they are not reconciled.
+func mergeConfig[T any](current, desired *T) []ConfigChange {
+	var changes []ConfigChange
+
+	cur := reflect.ValueOf(current).Elem()
+	des := reflect.ValueOf(desired).Elem()
+
+	for i := 0; i < des.NumField(); i++ {
+		field := des.Type().Field(i)
+		value := des.Field(i)
+
+		if !field.IsExported() || value.IsZero() {
+			continue
+		}
+
+		if reflect.DeepEqual(cur.Field(i).Interface(), value.Interface()) {
+			continue
+		}
+
+		changes = append(changes, ConfigChange{
+			Field: field.Name,
+			From:  formatValue(cur.Field(i)),
+			To:    formatValue(value),
+		})
+
+		cur.Field(i).Set(value)
+	}
+
+	return changes
+}
+
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "<nil>"
+		}
+
+		v = v.Elem()
+	}
+
+	return fmt.Sprintf("%v", v.Interface())
+}
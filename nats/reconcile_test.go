@@ -0,0 +1,48 @@
+package mynats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeConfig(t *testing.T) {
+	current := nats.ConsumerConfig{
+		Durable:       "billing",
+		AckPolicy:     nats.AckExplicitPolicy,
+		AckWait:       30 * time.Second,
+		MaxDeliver:    5,
+		MaxAckPending: 1000,
+	}
+
+	desired := nats.ConsumerConfig{
+		Durable:    "billing",
+		AckPolicy:  nats.AckExplicitPolicy,
+		MaxDeliver: 10,
+		BackOff:    []time.Duration{time.Second, time.Minute},
+	}
+
+	changes := mergeConfig(&current, &desired)
+
+	require.Equal(t, []ConfigChange{
+		{Field: "MaxDeliver", From: "5", To: "10"},
+		{Field: "BackOff", From: "[]", To: "[1s 1m0s]"},
+	}, changes)
+	require.Equal(t, 10, current.MaxDeliver)
+	require.Equal(t, 30*time.Second, current.AckWait)
+	require.Equal(t, 1000, current.MaxAckPending)
+
+	require.Empty(t, mergeConfig(&current, &desired))
+}
+
+func TestConfigDiff_String(t *testing.T) {
+	require.Equal(t, "ORDERS: created", ConfigDiff{Name: "ORDERS", Created: true}.String())
+	require.Equal(t, "ORDERS: up to date", ConfigDiff{Name: "ORDERS"}.String())
+	require.Equal(
+		t,
+		"ORDERS: MaxMsgs 10 -> 20",
+		ConfigDiff{Name: "ORDERS", Changes: []ConfigChange{{Field: "MaxMsgs", From: "10", To: "20"}}}.String(),
+	)
+}
@@ -0,0 +1,132 @@
+package mynats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kazzess/libraries/apperror"
+	"github.com/Kazzess/libraries/tracing"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	defaultRequestTimeout = 5 * time.Second
+
+	// HeaderAppError carries the JSON encoded apperror.AppError of the failed request.
+	HeaderAppError = "App-Error"
+)
+
+// ServiceError is the error reply of the NATS micro protocol without an apperror.
+type ServiceError struct {
+	Code        string
+	Description string
+}
+
+func (e *ServiceError) Error() string {
+	return fmt.Sprintf("service error %s: %s", e.Code, e.Description)
+}
+
+// WithRequestTimeout sets the timeout of requests without the context deadline, 5 seconds by default.
+func WithRequestTimeout(timeout time.Duration) OptionSetter {
+	return func(c *Config) { c.timeout = timeout }
+}
+
+// Request sends data and waits for the reply using core NATS. Error replies of the NATS
+// micro protocol are returned as *apperror.AppError if encoded by Service, else as *ServiceError.
+func (c *Client) Request(ctx context.Context, subject string, data []byte) (_ *Msg, err error) {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+
+	ctx, span := c.startProducerSpan(ctx, trace.SpanKindClient, msg)
+	defer span.End()
+
+	defer func() {
+		tracing.Error(ctx, err)
+	}()
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Config.timeout)
+		defer cancel()
+	}
+
+	defer observeRequest(subject, time.Now(), &err)
+
+	reply, err := c.nc.RequestMsgWithContext(ctx, msg)
+	if err != nil {
+		return nil, fmt.Errorf("Request: %w", err)
+	}
+
+	if err = replyError(reply); err != nil {
+		return reply, err
+	}
+
+	return reply, nil
+}
+
+// replyError decodes the error of the micro protocol reply.
+func replyError(reply *nats.Msg) error {
+	description := reply.Header.Get(micro.ErrorHeader)
+	code := reply.Header.Get(micro.ErrorCodeHeader)
+
+	if description == "" && code == "" {
+		return nil
+	}
+
+	if encoded := reply.Header.Get(HeaderAppError); encoded != "" {
+		appErr := &apperror.AppError{}
+		if json.Unmarshal([]byte(encoded), appErr) == nil {
+			appErr.Err = errors.New(appErr.Message)
+
+			return appErr
+		}
+	}
+
+	return &ServiceError{Code: code, Description: description}
+}
+
+// errorReply returns the micro protocol code, description and headers of the handler error.
+func errorReply(err error) (code, description string, headers micro.Headers) {
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) {
+		return strconv.Itoa(http.StatusInternalServerError), http.StatusText(http.StatusInternalServerError), nil
+	}
+
+	status := httpStatus(appErr.GRPCStatus().Code())
+
+	description = appErr.Message
+	if description == "" {
+		description = http.StatusText(status)
+	}
+
+	return strconv.Itoa(status), description, micro.Headers{HeaderAppError: []string{string(appErr.Marshal())}}
+}
+
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.AlreadyExists:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,43 @@
+package mynats
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Kazzess/libraries/apperror"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorReply(t *testing.T) {
+	code, description, headers := errorReply(apperror.NewNotFoundError("ORD", apperror.WithMessage("order not found")))
+	require.Equal(t, "404", code)
+	require.Equal(t, "order not found", description)
+
+	reply := nats.NewMsg("")
+	reply.Header.Set(micro.ErrorHeader, description)
+	reply.Header.Set(micro.ErrorCodeHeader, code)
+	reply.Header.Set(HeaderAppError, headers.Get(HeaderAppError))
+
+	var appErr *apperror.AppError
+	require.ErrorAs(t, replyError(reply), &appErr)
+	require.Equal(t, "ORD", appErr.SystemCode)
+	require.Equal(t, "order not found", appErr.Error())
+	require.False(t, appErr.IsRetryable())
+
+	code, description, headers = errorReply(errors.New("db is down"))
+	require.Equal(t, "500", code)
+	require.Equal(t, "Internal Server Error", description)
+	require.Nil(t, headers)
+
+	reply = nats.NewMsg("")
+	reply.Header.Set(micro.ErrorHeader, description)
+	reply.Header.Set(micro.ErrorCodeHeader, code)
+
+	var serviceErr *ServiceError
+	require.ErrorAs(t, replyError(reply), &serviceErr)
+	require.Equal(t, "500", serviceErr.Code)
+
+	require.NoError(t, replyError(nats.NewMsg("")))
+}
@@ -0,0 +1,152 @@
+package mynats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Kazzess/libraries/logging"
+	"github.com/Kazzess/libraries/tracing"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestHandler returns the reply data. Errors are sent as micro protocol error replies.
+type RequestHandler func(ctx context.Context, msg *Msg) ([]byte, error)
+
+type ServiceConfig struct {
+	description string
+	queueGroup  string
+	metadata    map[string]string
+}
+
+type ServiceOption func(*ServiceConfig)
+
+func WithServiceDescription(desc string) ServiceOption {
+	return func(c *ServiceConfig) {
+		c.description = desc
+	}
+}
+
+// WithServiceQueueGroup sets the queue group of the endpoints, "q" by default.
+func WithServiceQueueGroup(group string) ServiceOption {
+	return func(c *ServiceConfig) {
+		c.queueGroup = group
+	}
+}
+
+func WithServiceMetadata(metadata map[string]string) ServiceOption {
+	return func(c *ServiceConfig) {
+		c.metadata = metadata
+	}
+}
+
+// Service responds to requests with the NATS micro protocol, so it is discoverable by
+// `nats micro` and its stats are available with $SRV.STATS.
+type Service struct {
+	client     *Client
+	service    micro.Service
+	ctx        context.Context
+	queueGroup string
+}
+
+// AddService registers the service. The version must be SemVer compatible.
+func (c *Client) AddService(ctx context.Context, name, version string, options ...ServiceOption) (*Service, error) {
+	config := &ServiceConfig{}
+
+	for _, o := range options {
+		o(config)
+	}
+
+	svc, err := micro.AddService(c.nc, micro.Config{
+		Name:        name,
+		Version:     version,
+		Description: config.description,
+		Metadata:    config.metadata,
+		QueueGroup:  config.queueGroup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("AddService: %w", err)
+	}
+
+	queueGroup := config.queueGroup
+	if queueGroup == "" {
+		queueGroup = micro.DefaultQueueGroup
+	}
+
+	return &Service{client: c, service: svc, ctx: context.WithoutCancel(ctx), queueGroup: queueGroup}, nil
+}
+
+// Handle adds the endpoint responding on the subject with the service queue group.
+func (s *Service) Handle(name, subject string, handler RequestHandler) error {
+	err := s.service.AddEndpoint(name, micro.HandlerFunc(func(req micro.Request) {
+		s.respond(req, handler)
+	}), micro.WithEndpointSubject(subject))
+	if err != nil {
+		return fmt.Errorf("AddEndpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) respond(req micro.Request, handler RequestHandler) {
+	msg := &nats.Msg{
+		Subject: req.Subject(),
+		Reply:   req.Reply(),
+		Header:  nats.Header(req.Headers()),
+		Data:    req.Data(),
+	}
+
+	ctx, span := s.client.startConsumerSpan(s.ctx, trace.SpanKindServer, s.queueGroup, msg)
+	defer span.End()
+
+	start := time.Now()
+
+	data, err := safeRespond(ctx, handler, msg)
+
+	observeResponse(msg.Subject, start, err)
+
+	if err != nil {
+		tracing.Error(ctx, err)
+
+		logging.WithAttrs(
+			ctx,
+			logging.ErrAttr(err),
+			logging.StringAttr("subject", msg.Subject),
+		).Error("handle request error")
+
+		code, description, headers := errorReply(err)
+		err = req.Error(code, description, nil, micro.WithHeaders(headers))
+	} else {
+		err = req.Respond(data)
+	}
+
+	if err != nil {
+		logging.WithAttrs(ctx, logging.ErrAttr(err), logging.StringAttr("subject", msg.Subject)).
+			Error("send reply error")
+	}
+}
+
+func safeRespond(ctx context.Context, handler RequestHandler, msg *nats.Msg) (data []byte, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("request handler panic: %v", p)
+		}
+	}()
+
+	return handler(ctx, msg)
+}
+
+// Stop drains the endpoints, so requests in progress are replied.
+func (s *Service) Stop() error {
+	return s.service.Stop()
+}
+
+func (s *Service) Info() micro.Info {
+	return s.service.Info()
+}
+
+func (s *Service) Stats() micro.Stats {
+	return s.service.Stats()
+}
@@ -0,0 +1,119 @@
+package mynats
+
+import (
+	"context"
+
+	"github.com/Kazzess/libraries/tracing"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	messagingSystem = "nats"
+
+	operationSend    = "send"
+	operationProcess = "process"
+
+	attrMessageData    = attribute.Key("messaging.nats.message.data")
+	attrStream         = attribute.Key("messaging.nats.stream")
+	attrStreamSequence = attribute.Key("messaging.nats.stream.sequence")
+	attrDelivered      = attribute.Key("messaging.nats.delivered")
+	attrAckAction      = attribute.Key("messaging.nats.ack_action")
+)
+
+// WithTracePayload sets whether spans record message data, true by default.
+// Disable it for sensitive or large payloads.
+func WithTracePayload(enabled bool) OptionSetter {
+	return func(c *Config) { c.payload = enabled }
+}
+
+// startProducerSpan starts the span of the sent message following the OpenTelemetry
+// messaging conventions and injects its context into the message headers.
+// The span is a noop if tracing is disabled.
+func (c *Client) startProducerSpan(ctx context.Context, kind trace.SpanKind, msg *nats.Msg) (context.Context, trace.Span) {
+	var span trace.Span = noop.Span{}
+
+	if c.Config.tracing {
+		attrs := append(c.messageAttributes(operationSend, msg), semconv.MessagingOperationTypeSend)
+
+		ctx, span = tracing.Start(
+			ctx,
+			operationSend+" "+msg.Subject,
+			trace.WithSpanKind(kind),
+			trace.WithAttributes(attrs...),
+		)
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(msg.Header))
+
+	return ctx, span
+}
+
+// startConsumerSpan returns the context of the received message continuing the producer
+// trace. The consumer span is the child of the producer span and is linked to it, messages
+// without the trace context start a new trace. The span is a noop if tracing is disabled.
+func (c *Client) startConsumerSpan(
+	ctx context.Context,
+	kind trace.SpanKind,
+	consumerID string,
+	msg *nats.Msg,
+) (context.Context, trace.Span) {
+	if msg.Header == nil {
+		msg.Header = make(nats.Header)
+	}
+
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(msg.Header))
+
+	if !c.Config.tracing {
+		return ctx, noop.Span{}
+	}
+
+	attrs := append(c.messageAttributes(operationProcess, msg), semconv.MessagingOperationTypeProcess)
+	if consumerID != "" {
+		attrs = append(attrs, semconv.MessagingConsumerGroupName(consumerID))
+	}
+
+	options := []trace.SpanStartOption{trace.WithSpanKind(kind), trace.WithAttributes(attrs...)}
+
+	if producer := trace.SpanContextFromContext(ctx); producer.IsRemote() {
+		options = append(options, trace.WithLinks(trace.Link{SpanContext: producer}))
+	} else {
+		options = append(options, trace.WithNewRoot())
+	}
+
+	return tracing.Start(ctx, operationProcess+" "+msg.Subject, options...)
+}
+
+// messageAttributes returns the messaging attributes of the message.
+func (c *Client) messageAttributes(operation string, msg *nats.Msg) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystemKey.String(messagingSystem),
+		semconv.MessagingOperationName(operation),
+		semconv.MessagingDestinationName(msg.Subject),
+		semconv.MessagingMessageBodySize(len(msg.Data)),
+	}
+
+	if id := msg.Header.Get(nats.MsgIdHdr); id != "" {
+		attrs = append(attrs, semconv.MessagingMessageID(id))
+	}
+
+	if metadata, err := msg.Metadata(); err == nil {
+		attrs = append(
+			attrs,
+			attrStream.String(metadata.Stream),
+			attrStreamSequence.Int64(int64(metadata.Sequence.Stream)),
+			attrDelivered.Int64(int64(metadata.NumDelivered)),
+		)
+	}
+
+	if c.Config.payload {
+		attrs = append(attrs, attrMessageData.String(string(msg.Data)))
+	}
+
+	return attrs
+}
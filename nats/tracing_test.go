@@ -0,0 +1,127 @@
+package mynats
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setTestTracer(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	return recorder
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+func TestClient_MessagingSpans(t *testing.T) {
+	recorder := setTestTracer(t)
+
+	client := &Client{Config: NewConfig(nil, "", WithTracing(true))}
+
+	msg := nats.NewMsg("orders.created")
+	msg.Data = []byte("payload")
+	msg.Header.Set(nats.MsgIdHdr, "order-1")
+
+	_, producer := client.startProducerSpan(context.Background(), trace.SpanKindProducer, msg)
+	producer.End()
+
+	require.NotEmpty(t, propagation.HeaderCarrier(msg.Header).Get("traceparent"))
+
+	_, consumer := client.startConsumerSpan(context.Background(), trace.SpanKindConsumer, "billing", msg)
+	consumer.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	sent, processed := spans[0], spans[1]
+
+	require.Equal(t, "send orders.created", sent.Name())
+	require.Equal(t, trace.SpanKindProducer, sent.SpanKind())
+
+	for key, want := range map[attribute.Key]string{
+		"messaging.system":           "nats",
+		"messaging.operation.type":   "send",
+		"messaging.destination.name": "orders.created",
+		"messaging.message.id":       "order-1",
+		attrMessageData:              "payload",
+	} {
+		value, ok := spanAttr(sent, key)
+		require.True(t, ok, key)
+		require.Equal(t, want, value.AsString(), key)
+	}
+
+	size, _ := spanAttr(sent, "messaging.message.body.size")
+	require.EqualValues(t, 7, size.AsInt64())
+
+	require.Equal(t, "process orders.created", processed.Name())
+	require.Equal(t, trace.SpanKindConsumer, processed.SpanKind())
+	require.Equal(t, sent.SpanContext().SpanID(), processed.Parent().SpanID())
+	require.Len(t, processed.Links(), 1)
+	require.Equal(t, sent.SpanContext().SpanID(), processed.Links()[0].SpanContext.SpanID())
+
+	group, _ := spanAttr(processed, "messaging.consumer.group.name")
+	require.Equal(t, "billing", group.AsString())
+}
+
+func TestClient_MessagingSpansOptions(t *testing.T) {
+	recorder := setTestTracer(t)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	defer parent.End()
+
+	client := &Client{Config: NewConfig(nil, "", WithTracing(true), WithTracePayload(false))}
+
+	msg := nats.NewMsg("orders.created")
+	msg.Data = []byte("secret")
+
+	// The message without the trace context starts a new trace.
+	_, consumer := client.startConsumerSpan(ctx, trace.SpanKindConsumer, "", msg)
+	consumer.End()
+
+	processed := recorder.Ended()[0]
+	require.False(t, processed.Parent().IsValid())
+	require.Empty(t, processed.Links())
+
+	_, ok := spanAttr(processed, attrMessageData)
+	require.False(t, ok)
+
+	// Disabled tracing records no spans but propagates the trace context.
+	client = &Client{Config: NewConfig(nil, "")}
+
+	msg = nats.NewMsg("orders.created")
+	_, producer := client.startProducerSpan(ctx, trace.SpanKindProducer, msg)
+	producer.End()
+
+	msgCtx, consumer := client.startConsumerSpan(context.Background(), trace.SpanKindConsumer, "", msg)
+	consumer.End()
+
+	require.Len(t, recorder.Ended(), 1)
+	require.Equal(t, parent.SpanContext().TraceID(), trace.SpanContextFromContext(msgCtx).TraceID())
+}
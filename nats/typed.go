@@ -0,0 +1,162 @@
+package mynats
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	HeaderContentType   = "Content-Type"
+	HeaderSchema        = "Message-Schema"
+	HeaderSchemaVersion = "Message-Schema-Version"
+)
+
+// Schema is implemented by messages which set the schema headers explicitly.
+// By default the schema is the proto full name or the Go type name without version.
+type Schema interface {
+	MessageSchema() (name, version string)
+}
+
+// Meta describes the message decoded by Subscribe.
+type Meta struct {
+	Subject       string
+	Header        nats.Header
+	ContentType   string
+	Schema        string
+	SchemaVersion string
+	// Metadata is nil for messages delivered not by JetStream.
+	Metadata *nats.MsgMetadata
+	Msg      *nats.Msg
+}
+
+type Handler[T any] func(ctx context.Context, v T, meta Meta) error
+
+// DecodeErrorHandler handles messages which Subscribe failed to decode. The returned error
+// is settled by the ack policy, nil acks the message.
+type DecodeErrorHandler func(ctx context.Context, msg *nats.Msg, err error) error
+
+// WithDecodeErrorHandler sets the decode error handler. By default decode errors are
+// permanent, so the message is terminated or dead lettered.
+func WithDecodeErrorHandler(handler DecodeErrorHandler) OptionSetter {
+	return func(c *Config) { c.decodeErr = handler }
+}
+
+func defaultDecodeErrorHandler(_ context.Context, _ *nats.Msg, err error) error {
+	return Permanent(err)
+}
+
+// Publish marshals v with the client codec and publishes it with the content type
+// and schema headers.
+func Publish[T any](ctx context.Context, c *Client, subject string, v T, opts ...PublishOption) error {
+	data, err := c.Config.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("Publish: marshal: %w", err)
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	msg.Header.Set(HeaderContentType, c.Config.codec.ContentType())
+
+	name, version := schemaOf(v)
+	if name != "" {
+		msg.Header.Set(HeaderSchema, name)
+	}
+
+	if version != "" {
+		msg.Header.Set(HeaderSchemaVersion, version)
+	}
+
+	if err = c.publishMsg(ctx, msg, opts...); err != nil {
+		return fmt.Errorf("Publish: %w", err)
+	}
+
+	return nil
+}
+
+// Subscribe decodes messages with the codec selected by the Content-Type header and passes
+// them to the handler. Messages are settled by the ack policy as in SubscribeAsync.
+func Subscribe[T any](
+	ctx context.Context,
+	c *Client,
+	subject, consumerID string,
+	handler Handler[T],
+	opts ...SubscribeOption,
+) error {
+	return c.SubscribeAsync(ctx, subject, consumerID, func(ctx context.Context, msg *nats.Msg) error {
+		v, meta, err := decodeMsg[T](c.Config, msg)
+		if err != nil {
+			return c.Config.decodeErr(ctx, msg, err)
+		}
+
+		return handler(ctx, v, meta)
+	}, opts...)
+}
+
+func decodeMsg[T any](config *Config, msg *nats.Msg) (T, Meta, error) {
+	meta := Meta{
+		Subject:       msg.Subject,
+		Header:        msg.Header,
+		ContentType:   msg.Header.Get(HeaderContentType),
+		Schema:        msg.Header.Get(HeaderSchema),
+		SchemaVersion: msg.Header.Get(HeaderSchemaVersion),
+		Msg:           msg,
+	}
+
+	if metadata, err := msg.Metadata(); err == nil {
+		meta.Metadata = metadata
+	}
+
+	codec, err := config.codecFor(meta.ContentType)
+	if err != nil {
+		var zero T
+		return zero, meta, err
+	}
+
+	v, err := decode[T](codec, msg.Data)
+	if err != nil {
+		return v, meta, fmt.Errorf("decode %s: %w", codec.ContentType(), err)
+	}
+
+	return v, meta, nil
+}
+
+// decode unmarshals data into T. Pointer types are allocated, so *T proto messages
+// are decoded in place.
+func decode[T any](codec Codec, data []byte) (T, error) {
+	var v T
+
+	target := any(&v)
+
+	if t := reflect.TypeFor[T](); t.Kind() == reflect.Pointer {
+		v = reflect.New(t.Elem()).Interface().(T)
+		target = v
+	}
+
+	err := codec.Unmarshal(data, target)
+
+	return v, err
+}
+
+func schemaOf(v any) (name, version string) {
+	switch m := v.(type) {
+	case Schema:
+		return m.MessageSchema()
+	case proto.Message:
+		return string(proto.MessageName(m)), ""
+	}
+
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == nil {
+		return "", ""
+	}
+
+	return t.String(), ""
+}
@@ -0,0 +1,57 @@
+package outbox
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Kazzess/libraries/metrics"
+)
+
+var (
+	publishedTotal = metrics.NewCounterVec(
+		metrics.CounterOpts{
+			Name: "outbox_published_total",
+			Help: "The number of outbox events publish attempts",
+		},
+		[]string{"table", "is_err"},
+	)
+
+	// deliveryTimeMs is a histogram that measures the time from adding an event till its publishing (milliseconds).
+	deliveryTimeMs = metrics.NewHistogramVec(
+		metrics.HistogramOpts{
+			Name:    "outbox_delivery_time_ms",
+			Help:    "The time from adding an outbox event till its publishing (milliseconds)",
+			Buckets: []float64{10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000, 300000},
+		},
+		[]string{"table"},
+	)
+
+	backlogSize = metrics.NewGaugeVec(
+		metrics.GaugeOpts{
+			Name: "outbox_backlog",
+			Help: "The number of outbox events waiting for publishing",
+		},
+		[]string{"table"},
+	)
+
+	lagMs = metrics.NewGaugeVec(
+		metrics.GaugeOpts{
+			Name: "outbox_lag_ms",
+			Help: "The age of the oldest outbox event waiting for publishing (milliseconds)",
+		},
+		[]string{"table"},
+	)
+)
+
+func observePublish(table string, err error, createdAt time.Time) {
+	publishedTotal.WithLabelValues(table, strconv.FormatBool(err != nil)).Inc()
+
+	if err == nil {
+		deliveryTimeMs.WithLabelValues(table).Observe(float64(time.Since(createdAt).Milliseconds()))
+	}
+}
+
+func setBacklog(table string, backlog int64, lag float64) {
+	backlogSize.WithLabelValues(table).Set(float64(backlog))
+	lagMs.WithLabelValues(table).Set(lag)
+}
@@ -0,0 +1,128 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	psql "github.com/Kazzess/libraries/postgresql"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const defaultTable = "outbox"
+
+var ErrNoTransaction = errors.New("outbox events must be added inside a transaction")
+
+// Event is a message published to Subject after the transaction commits.
+// ID is sent as Nats-Msg-Id, so JetStream drops duplicates published by retries.
+// Empty ID is generated.
+type Event struct {
+	ID      string
+	Subject string
+	Payload []byte
+}
+
+type Config struct {
+	table string
+}
+
+type Option func(*Config)
+
+// WithTable sets the outbox table, "outbox" by default. The name may be schema qualified.
+func WithTable(table string) Option {
+	return func(cfg *Config) {
+		cfg.table = table
+	}
+}
+
+type Outbox struct {
+	db     *psql.Client
+	config *Config
+	table  string
+}
+
+func New(db *psql.Client, options ...Option) *Outbox {
+	config := &Config{table: defaultTable}
+
+	for _, o := range options {
+		o(config)
+	}
+
+	return &Outbox{
+		db:     db,
+		config: config,
+		table:  identifier(config.table),
+	}
+}
+
+// Schema returns the DDL of the outbox table. Add it to migrations or call CreateTable.
+func (o *Outbox) Schema() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+	id BIGSERIAL PRIMARY KEY,
+	msg_id TEXT NOT NULL UNIQUE,
+	subject TEXT NOT NULL,
+	payload BYTEA NOT NULL,
+	headers JSONB NOT NULL DEFAULT '{}',
+	attempts INT NOT NULL DEFAULT 0,
+	last_error TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	sent_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (next_attempt_at) WHERE sent_at IS NULL;`,
+		o.table,
+		pgx.Identifier{o.config.table[strings.LastIndex(o.config.table, ".")+1:] + "_pending_idx"}.Sanitize(),
+	)
+}
+
+func (o *Outbox) CreateTable(ctx context.Context) error {
+	if _, err := o.db.Exec(ctx, o.Schema()); err != nil {
+		return fmt.Errorf("failed to create outbox table due to error: %w", err)
+	}
+
+	return nil
+}
+
+// Add writes events into the outbox within the transaction started by psql.Client.WithTx,
+// so events are stored only if the transaction commits. The trace context of ctx is
+// saved to the event headers and restored by the relay.
+func (o *Outbox) Add(ctx context.Context, events ...Event) error {
+	if _, ok := psql.TxFromContext(ctx); !ok {
+		return ErrNoTransaction
+	}
+
+	for _, event := range events {
+		if event.ID == "" {
+			event.ID = uuid.NewString()
+		}
+
+		headers := make(map[string]string)
+		otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+
+		encodedHeaders, err := json.Marshal(headers)
+		if err != nil {
+			return fmt.Errorf("failed to marshal outbox headers due to error: %w", err)
+		}
+
+		_, err = o.db.Exec(
+			ctx,
+			`INSERT INTO `+o.table+` (msg_id, subject, payload, headers) VALUES ($1, $2, $3, $4)`,
+			event.ID, event.Subject, event.Payload, encodedHeaders,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to add outbox event due to error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// identifier quotes a possibly schema qualified name.
+func identifier(name string) string {
+	return pgx.Identifier(strings.Split(name, ".")).Sanitize()
+}
@@ -0,0 +1,30 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	psql "github.com/Kazzess/libraries/postgresql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox_AddRequiresTransaction(t *testing.T) {
+	o := New(&psql.Client{})
+
+	err := o.Add(context.Background(), Event{Subject: "orders.created", Payload: []byte(`{}`)})
+	require.ErrorIs(t, err, ErrNoTransaction)
+}
+
+func TestOutbox_Schema(t *testing.T) {
+	schema := New(nil, WithTable("events.outbox")).Schema()
+
+	require.Contains(t, schema, `CREATE TABLE IF NOT EXISTS "events"."outbox"`)
+	require.Contains(t, schema, `CREATE INDEX IF NOT EXISTS "outbox_pending_idx" ON "events"."outbox"`)
+}
+
+func TestRetryDelay(t *testing.T) {
+	require.Equal(t, time.Second, retryDelay(0, time.Second, time.Minute))
+	require.Equal(t, 8*time.Second, retryDelay(3, time.Second, time.Minute))
+	require.Equal(t, time.Minute, retryDelay(10, time.Second, time.Minute))
+}
@@ -0,0 +1,332 @@
+package outbox
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/Kazzess/libraries/logging"
+	mynats "github.com/Kazzess/libraries/nats"
+	psql "github.com/Kazzess/libraries/postgresql"
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	defaultPollInterval    = time.Second
+	defaultBatchSize       = 100
+	defaultMinRetryDelay   = time.Second
+	defaultMaxRetryDelay   = 5 * time.Minute
+	defaultRetention       = 24 * time.Hour
+	defaultCleanupInterval = time.Minute
+	defaultLease           = time.Minute
+	retryBackoffFactor     = 2
+	maxErrorLength         = 1024
+)
+
+// Publisher is implemented by mynats.Client.
+type Publisher interface {
+	PublishSync(ctx context.Context, subject string, data []byte, opts ...mynats.PublishOption) error
+}
+
+var _ Publisher = (*mynats.Client)(nil)
+
+type RelayConfig struct {
+	pollInterval  time.Duration
+	batchSize     int
+	minRetryDelay time.Duration
+	maxRetryDelay time.Duration
+	retention     time.Duration
+	lease         time.Duration
+}
+
+type RelayOption func(*RelayConfig)
+
+// WithPollInterval sets how often the relay looks for pending events, a second by default.
+func WithPollInterval(interval time.Duration) RelayOption {
+	return func(cfg *RelayConfig) {
+		cfg.pollInterval = interval
+	}
+}
+
+// WithBatchSize sets the number of events claimed at once, 100 by default.
+func WithBatchSize(size int) RelayOption {
+	return func(cfg *RelayConfig) {
+		cfg.batchSize = size
+	}
+}
+
+// WithRetryDelay sets the exponential backoff bounds for failed events.
+func WithRetryDelay(minDelay, maxDelay time.Duration) RelayOption {
+	return func(cfg *RelayConfig) {
+		cfg.minRetryDelay = minDelay
+		cfg.maxRetryDelay = maxDelay
+	}
+}
+
+// WithRetention sets how long sent events are kept, a day by default.
+// Non-positive value keeps them forever.
+func WithRetention(retention time.Duration) RelayOption {
+	return func(cfg *RelayConfig) {
+		cfg.retention = retention
+	}
+}
+
+// WithLease sets how long claimed events are hidden from other relays, a minute by default.
+// Events not published within the lease, e.g. after a crash, are claimed again, so it should
+// exceed the time of publishing a batch.
+func WithLease(lease time.Duration) RelayOption {
+	return func(cfg *RelayConfig) {
+		cfg.lease = lease
+	}
+}
+
+// Relay publishes events written by Outbox.Add.
+type Relay struct {
+	outbox      *Outbox
+	publisher   Publisher
+	config      *RelayConfig
+	lastCleanup time.Time
+}
+
+func (o *Outbox) NewRelay(publisher Publisher, options ...RelayOption) *Relay {
+	config := &RelayConfig{
+		pollInterval:  defaultPollInterval,
+		batchSize:     defaultBatchSize,
+		minRetryDelay: defaultMinRetryDelay,
+		maxRetryDelay: defaultMaxRetryDelay,
+		retention:     defaultRetention,
+		lease:         defaultLease,
+	}
+
+	for _, o := range options {
+		o(config)
+	}
+
+	return &Relay{
+		outbox:    o,
+		publisher: publisher,
+		config:    config,
+	}
+}
+
+type pendingEvent struct {
+	id        int64
+	msgID     string
+	subject   string
+	payload   []byte
+	headers   []byte
+	attempts  int
+	createdAt time.Time
+}
+
+// Run publishes pending events until ctx is done. Events are claimed for the lease in
+// a short statement and published outside of transactions, so several relays may run
+// concurrently without holding row locks during publishing. Events are published in
+// insertion order, but a failed event is retried with backoff without blocking the next ones.
+// Events may be published twice if the relay stops before marking them sent,
+// the Nats-Msg-Id header lets JetStream drop such duplicates.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.config.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		processed, err := r.publishBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			logging.WithAttrs(ctx, logging.ErrAttr(err)).Error("failed to relay outbox events")
+		}
+
+		r.observeBacklog(ctx)
+		r.cleanup(ctx)
+
+		// A full batch means there may be more events, so poll again immediately.
+		if err == nil && processed == r.config.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) publishBatch(ctx context.Context) (int, error) {
+	events, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var processed int
+
+	for i, event := range events {
+		if err = r.publish(ctx, event); err != nil {
+			r.release(ctx, events[i:])
+
+			return processed, err
+		}
+
+		processed++
+	}
+
+	return processed, nil
+}
+
+// release clears the lease of the events left after the batch is aborted,
+// so they are published without waiting for the lease to expire.
+func (r *Relay) release(ctx context.Context, events []pendingEvent) {
+	ids := make([]int64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.id)
+	}
+
+	_, err := r.outbox.db.Exec(
+		context.WithoutCancel(ctx),
+		`UPDATE `+r.outbox.table+` SET next_attempt_at = now() WHERE id = ANY($1) AND sent_at IS NULL`,
+		ids,
+	)
+	if err != nil {
+		logging.WithAttrs(ctx, logging.IntAttr("events", len(ids)), logging.ErrAttr(err)).
+			Error("failed to release outbox events")
+	}
+}
+
+// claim postpones the next attempt of pending events by the lease and returns them
+// ordered by ID. Rows locked by other relays are skipped.
+func (r *Relay) claim(ctx context.Context) ([]pendingEvent, error) {
+	rows, err := r.outbox.db.Query(
+		ctx,
+		`UPDATE `+r.outbox.table+` SET next_attempt_at = now() + $2 * interval '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM `+r.outbox.table+`
+			WHERE sent_at IS NULL AND next_attempt_at <= now()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, msg_id, subject, payload, headers, attempts, created_at`,
+		r.config.batchSize,
+		r.config.lease.Milliseconds(),
+	)
+	if err != nil {
+		return nil, psql.ErrDoQuery(err)
+	}
+
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (pendingEvent, error) {
+		var e pendingEvent
+		err := row.Scan(&e.id, &e.msgID, &e.subject, &e.payload, &e.headers, &e.attempts, &e.createdAt)
+
+		return e, err
+	})
+	if err != nil {
+		return nil, psql.ErrScan(err)
+	}
+
+	// RETURNING doesn't keep the order of the subquery.
+	slices.SortFunc(events, func(a, b pendingEvent) int {
+		return cmp.Compare(a.id, b.id)
+	})
+
+	return events, nil
+}
+
+func (r *Relay) publish(ctx context.Context, event pendingEvent) error {
+	headers := make(map[string]string)
+	_ = json.Unmarshal(event.headers, &headers)
+
+	publishCtx := otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+
+	publishErr := r.publisher.PublishSync(publishCtx, event.subject, event.payload, nats.MsgId(event.msgID))
+
+	observePublish(r.outbox.config.table, publishErr, event.createdAt)
+
+	if publishErr != nil {
+		logging.WithAttrs(
+			ctx,
+			logging.StringAttr("subject", event.subject),
+			logging.StringAttr("msg_id", event.msgID),
+			logging.IntAttr("attempts", event.attempts+1),
+			logging.ErrAttr(publishErr),
+		).Warn("failed to publish outbox event")
+
+		_, err := r.outbox.db.Exec(
+			ctx,
+			`UPDATE `+r.outbox.table+`
+			SET attempts = attempts + 1, last_error = $2, next_attempt_at = now() + $3 * interval '1 millisecond'
+			WHERE id = $1`,
+			event.id,
+			truncate(publishErr.Error(), maxErrorLength),
+			retryDelay(event.attempts, r.config.minRetryDelay, r.config.maxRetryDelay).Milliseconds(),
+		)
+
+		return err
+	}
+
+	_, err := r.outbox.db.Exec(ctx, `UPDATE `+r.outbox.table+` SET sent_at = now() WHERE id = $1`, event.id)
+
+	return err
+}
+
+func (r *Relay) observeBacklog(ctx context.Context) {
+	var (
+		backlog int64
+		lagMs   float64
+	)
+
+	err := r.outbox.db.QueryRow(
+		ctx,
+		`SELECT count(*), COALESCE(EXTRACT(EPOCH FROM now() - min(created_at)) * 1000, 0)
+		FROM `+r.outbox.table+` WHERE sent_at IS NULL`,
+	).Scan(&backlog, &lagMs)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			logging.WithAttrs(ctx, logging.ErrAttr(err)).Error("failed to observe outbox backlog")
+		}
+
+		return
+	}
+
+	setBacklog(r.outbox.config.table, backlog, lagMs)
+}
+
+func (r *Relay) cleanup(ctx context.Context) {
+	if r.config.retention <= 0 || time.Since(r.lastCleanup) < defaultCleanupInterval {
+		return
+	}
+
+	r.lastCleanup = time.Now()
+
+	_, err := r.outbox.db.Exec(
+		ctx,
+		`DELETE FROM `+r.outbox.table+` WHERE sent_at < now() - $1 * interval '1 millisecond'`,
+		r.config.retention.Milliseconds(),
+	)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		logging.WithAttrs(ctx, logging.ErrAttr(err)).Error("failed to clean up outbox")
+	}
+}
+
+// retryDelay returns minDelay * 2^attempts capped by maxDelay.
+func retryDelay(attempts int, minDelay, maxDelay time.Duration) time.Duration {
+	delay := float64(minDelay) * math.Pow(retryBackoffFactor, float64(attempts))
+	if delay > float64(maxDelay) {
+		return maxDelay
+	}
+
+	return time.Duration(delay)
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+
+	return s[:length]
+}
@@ -0,0 +1,283 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const defaultBulkChunkSize = 1000
+
+type BulkConfig struct {
+	chunkSize     int
+	progress      func(done, total int64)
+	updateColumns []string
+}
+
+type BulkOption func(*BulkConfig)
+
+// WithChunkSize sets the number of rows sent per COPY or batch, 1000 by default.
+func WithChunkSize(size int) BulkOption {
+	return func(cfg *BulkConfig) {
+		if size > 0 {
+			cfg.chunkSize = size
+		}
+	}
+}
+
+// WithProgress sets the callback called after every chunk with the number of processed
+// and total rows.
+func WithProgress(progress func(done, total int64)) BulkOption {
+	return func(cfg *BulkConfig) {
+		cfg.progress = progress
+	}
+}
+
+// WithUpdateColumns limits the columns updated by BulkUpsert on conflict.
+// By default all columns except the conflict ones are updated.
+func WithUpdateColumns(columns ...string) BulkOption {
+	return func(cfg *BulkConfig) {
+		cfg.updateColumns = columns
+	}
+}
+
+func newBulkConfig(options ...BulkOption) *BulkConfig {
+	config := &BulkConfig{chunkSize: defaultBulkChunkSize}
+
+	for _, o := range options {
+		o(config)
+	}
+
+	return config
+}
+
+// BulkInsert copies rows into the table with COPY. Columns are taken from the "db"
+// struct tags of T. Every chunk is a separate COPY, so run it in WithTx to insert
+// all rows or none. It returns the number of inserted rows.
+func BulkInsert[T any](ctx context.Context, c *Client, table string, rows []T, options ...BulkOption) (int64, error) {
+	cfg := newBulkConfig(options...)
+
+	fields, err := bulkFields[T]()
+	if err != nil {
+		return 0, err
+	}
+
+	if err = checkRows(rows); err != nil {
+		return 0, err
+	}
+
+	columns := fieldColumns(fields)
+	total := int64(len(rows))
+
+	var done int64
+
+	for chunk := range slices.Chunk(rows, cfg.chunkSize) {
+		copied, copyErr := c.CopyFrom(ctx, tableIdentifier(table), columns, pgx.CopyFromSlice(len(chunk), func(i int) ([]any, error) {
+			return structValues(chunk[i], fields)
+		}))
+		done += copied
+
+		if copyErr != nil {
+			return done, ErrCopyFrom(copyErr)
+		}
+
+		if cfg.progress != nil {
+			cfg.progress(done, total)
+		}
+	}
+
+	return done, nil
+}
+
+// BulkUpsert inserts rows with INSERT ... ON CONFLICT (conflictColumns) DO UPDATE
+// sending every chunk as a single pgx.Batch. If there is nothing to update the
+// conflicting rows are skipped. It returns the number of inserted or updated rows.
+func BulkUpsert[T any](
+	ctx context.Context,
+	c *Client,
+	table string,
+	rows []T,
+	conflictColumns []string,
+	options ...BulkOption,
+) (int64, error) {
+	cfg := newBulkConfig(options...)
+
+	fields, err := bulkFields[T]()
+	if err != nil {
+		return 0, err
+	}
+
+	if err = checkRows(rows); err != nil {
+		return 0, err
+	}
+
+	query := upsertQuery(table, fieldColumns(fields), conflictColumns, cfg.updateColumns)
+	total := int64(len(rows))
+
+	var done int64
+
+	for chunk := range slices.Chunk(rows, cfg.chunkSize) {
+		batch := &pgx.Batch{}
+
+		for _, row := range chunk {
+			values, valuesErr := structValues(row, fields)
+			if valuesErr != nil {
+				return done, valuesErr
+			}
+
+			batch.Queue(query, values...)
+		}
+
+		affected, batchErr := execBatch(ctx, c, batch)
+		done += affected
+
+		if batchErr != nil {
+			return done, ErrExecBatch(batchErr)
+		}
+
+		if cfg.progress != nil {
+			cfg.progress(done, total)
+		}
+	}
+
+	return done, nil
+}
+
+func execBatch(ctx context.Context, c *Client, batch *pgx.Batch) (affected int64, err error) {
+	results := c.SendBatch(ctx, batch)
+	defer func() {
+		err = errors.Join(err, results.Close())
+	}()
+
+	for range batch.Len() {
+		tag, execErr := results.Exec()
+		if execErr != nil {
+			return affected, execErr
+		}
+
+		affected += tag.RowsAffected()
+	}
+
+	return affected, nil
+}
+
+func upsertQuery(table string, columns, conflictColumns, updateColumns []string) string {
+	var b strings.Builder
+
+	b.WriteString("INSERT INTO ")
+	b.WriteString(tableIdentifier(table).Sanitize())
+	b.WriteString(" (")
+	b.WriteString(joinIdentifiers(columns))
+	b.WriteString(") VALUES (")
+
+	for i := range columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteString("$" + strconv.Itoa(i+1))
+	}
+
+	b.WriteString(") ON CONFLICT (")
+	b.WriteString(joinIdentifiers(conflictColumns))
+	b.WriteString(")")
+
+	if len(updateColumns) == 0 {
+		for _, column := range columns {
+			if !slices.Contains(conflictColumns, column) {
+				updateColumns = append(updateColumns, column)
+			}
+		}
+	}
+
+	if len(updateColumns) == 0 {
+		b.WriteString(" DO NOTHING")
+
+		return b.String()
+	}
+
+	b.WriteString(" DO UPDATE SET ")
+
+	for i, column := range updateColumns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		identifier := pgx.Identifier{column}.Sanitize()
+		b.WriteString(identifier + " = EXCLUDED." + identifier)
+	}
+
+	return b.String()
+}
+
+func joinIdentifiers(columns []string) string {
+	sanitized := make([]string, 0, len(columns))
+	for _, column := range columns {
+		sanitized = append(sanitized, pgx.Identifier{column}.Sanitize())
+	}
+
+	return strings.Join(sanitized, ", ")
+}
+
+func bulkFields[T any]() ([]structField, error) {
+	t := reflect.TypeFor[T]()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, ErrNotStruct
+	}
+
+	return structFields(t), nil
+}
+
+func fieldColumns(fields []structField) []string {
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		columns = append(columns, field.column)
+	}
+
+	return columns
+}
+
+func structValues(row any, fields []structField) ([]any, error) {
+	v := reflect.ValueOf(row)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, ErrNilRow
+		}
+
+		v = v.Elem()
+	}
+
+	values := make([]any, 0, len(fields))
+
+	for _, field := range fields {
+		values = append(values, fieldValue(v, field.index))
+	}
+
+	return values, nil
+}
+
+// checkRows returns ErrNilRow with the index of the first nil row, so nothing is
+// written if any row is nil.
+func checkRows[T any](rows []T) error {
+	if reflect.TypeFor[T]().Kind() != reflect.Pointer {
+		return nil
+	}
+
+	for i, row := range rows {
+		if reflect.ValueOf(row).IsNil() {
+			return fmt.Errorf("row %d: %w", i, ErrNilRow)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package psql
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type bulkAudit struct {
+	CreatedAt time.Time
+	UpdatedBy string `db:"updated_by"`
+}
+
+type bulkUser struct {
+	ID       int64  `db:"id"`
+	Email    string `db:"email"`
+	FullName string
+	Password string `db:"-"`
+	internal string
+	*bulkAudit
+}
+
+func TestStructFields(t *testing.T) {
+	fields := structFields(reflect.TypeFor[bulkUser]())
+
+	require.Equal(t, []string{"id", "email", "full_name", "created_at", "updated_by"}, fieldColumns(fields))
+
+	values, err := structValues(bulkUser{ID: 1, Email: "a@b.c", FullName: "A"}, fields)
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(1), "a@b.c", "A", nil, nil}, values)
+
+	now := time.Now()
+	values, err = structValues(&bulkUser{ID: 2, bulkAudit: &bulkAudit{CreatedAt: now, UpdatedBy: "admin"}}, fields)
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(2), "", "", now, "admin"}, values)
+
+	_, err = structValues((*bulkUser)(nil), fields)
+	require.ErrorIs(t, err, ErrNilRow)
+}
+
+func TestCheckRows(t *testing.T) {
+	require.NoError(t, checkRows([]bulkUser{{ID: 1}}))
+	require.NoError(t, checkRows([]*bulkUser{{ID: 1}}))
+
+	err := checkRows([]*bulkUser{{ID: 1}, nil})
+	require.ErrorIs(t, err, ErrNilRow)
+	require.ErrorContains(t, err, "row 1")
+}
+
+func TestToSnakeCase(t *testing.T) {
+	require.Equal(t, "user_id", toSnakeCase("UserID"))
+	require.Equal(t, "http_status", toSnakeCase("HTTPStatus"))
+	require.Equal(t, "name", toSnakeCase("Name"))
+}
+
+func TestUpsertQuery(t *testing.T) {
+	require.Equal(t,
+		`INSERT INTO "public"."users" ("id", "email", "name") VALUES ($1, $2, $3) `+
+			`ON CONFLICT ("id") DO UPDATE SET "email" = EXCLUDED."email", "name" = EXCLUDED."name"`,
+		upsertQuery("public.users", []string{"id", "email", "name"}, []string{"id"}, nil),
+	)
+
+	require.Equal(t,
+		`INSERT INTO "users" ("id", "email") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "email" = EXCLUDED."email"`,
+		upsertQuery("users", []string{"id", "email"}, []string{"id"}, []string{"email"}),
+	)
+
+	require.Equal(t,
+		`INSERT INTO "tags" ("name") VALUES ($1) ON CONFLICT ("name") DO NOTHING`,
+		upsertQuery("tags", []string{"name"}, []string{"name"}, nil),
+	)
+}
+
+func TestBulkFields(t *testing.T) {
+	_, err := bulkFields[int]()
+	require.ErrorIs(t, err, ErrNotStruct)
+
+	fields, err := bulkFields[*bulkUser]()
+	require.NoError(t, err)
+	require.Len(t, fields, 5)
+}
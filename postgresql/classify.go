@@ -0,0 +1,236 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/Kazzess/libraries/apperror"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type ErrorKind uint8
+
+const (
+	ErrorKindUnknown ErrorKind = iota
+	ErrorKindNoRows
+	ErrorKindUniqueViolation
+	ErrorKindForeignKeyViolation
+	ErrorKindNotNullViolation
+	ErrorKindCheckViolation
+	ErrorKindSerializationFailure
+	ErrorKindDeadlock
+	ErrorKindQueryCanceled
+	ErrorKindConnection
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindNoRows:
+		return "no_rows"
+	case ErrorKindUniqueViolation:
+		return "unique_violation"
+	case ErrorKindForeignKeyViolation:
+		return "foreign_key_violation"
+	case ErrorKindNotNullViolation:
+		return "not_null_violation"
+	case ErrorKindCheckViolation:
+		return "check_violation"
+	case ErrorKindSerializationFailure:
+		return "serialization_failure"
+	case ErrorKindDeadlock:
+		return "deadlock"
+	case ErrorKindQueryCanceled:
+		return "query_canceled"
+	case ErrorKindConnection:
+		return "connection"
+	default:
+		return "unknown"
+	}
+}
+
+// Error is a classified database error. Constraint, Table and Column are filled
+// from the server error when PostgreSQL reports them.
+type Error struct {
+	Kind       ErrorKind
+	SQLState   string
+	Message    string
+	Detail     string
+	Schema     string
+	Table      string
+	Column     string
+	Constraint string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.SQLState == "" {
+		return fmt.Sprintf("database error (%s): %v", e.Kind, e.Err)
+	}
+
+	return fmt.Sprintf(
+		"database error (%s). message:%s, detail:%s, constraint:%s, sqlstate:%s",
+		e.Kind,
+		e.Message,
+		e.Detail,
+		e.Constraint,
+		e.SQLState,
+	)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError returns the classified error or nil if err is nil.
+// Errors not recognized by the classifier have ErrorKindUnknown.
+func ClassifyError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified
+	}
+
+	classified = &Error{Kind: ErrorKindUnknown, Err: err}
+
+	var pgErr *PgError
+	if errors.As(err, &pgErr) {
+		classified.Kind = pgErrorKind(pgErr.Code)
+		classified.SQLState = pgErr.Code
+		classified.Message = pgErr.Message
+		classified.Detail = pgErr.Detail
+		classified.Schema = pgErr.SchemaName
+		classified.Table = pgErr.TableName
+		classified.Column = pgErr.ColumnName
+		classified.Constraint = pgErr.ConstraintName
+
+		return classified
+	}
+
+	var (
+		connectErr *pgconn.ConnectError
+		netErr     net.Error
+	)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		classified.Kind = ErrorKindNoRows
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		classified.Kind = ErrorKindQueryCanceled
+	case errors.As(err, &connectErr), errors.As(err, &netErr), pgconn.SafeToRetry(err):
+		classified.Kind = ErrorKindConnection
+	}
+
+	return classified
+}
+
+func pgErrorKind(code string) ErrorKind {
+	switch {
+	case code == pgerrcode.UniqueViolation:
+		return ErrorKindUniqueViolation
+	case code == pgerrcode.ForeignKeyViolation:
+		return ErrorKindForeignKeyViolation
+	case code == pgerrcode.NotNullViolation:
+		return ErrorKindNotNullViolation
+	case code == pgerrcode.CheckViolation:
+		return ErrorKindCheckViolation
+	case code == pgerrcode.SerializationFailure:
+		return ErrorKindSerializationFailure
+	case code == pgerrcode.DeadlockDetected:
+		return ErrorKindDeadlock
+	case code == pgerrcode.QueryCanceled:
+		return ErrorKindQueryCanceled
+	case pgerrcode.IsConnectionException(code),
+		code == pgerrcode.AdminShutdown,
+		code == pgerrcode.CrashShutdown,
+		code == pgerrcode.CannotConnectNow:
+		return ErrorKindConnection
+	default:
+		return ErrorKindUnknown
+	}
+}
+
+// IsErrorKind reports whether err is classified as kind.
+func IsErrorKind(err error, kind ErrorKind) bool {
+	classified := ClassifyError(err)
+
+	return classified != nil && classified.Kind == kind
+}
+
+type ErrorMapperConfig struct {
+	constraints map[string]func(*Error) *apperror.AppError
+}
+
+type ErrorMapperOption func(*ErrorMapperConfig)
+
+// WithConstraintError overrides the application error for violations of the constraint,
+// e.g. to return a validation error with a field name for "users_email_key".
+func WithConstraintError(constraint string, fn func(*Error) *apperror.AppError) ErrorMapperOption {
+	return func(cfg *ErrorMapperConfig) {
+		cfg.constraints[constraint] = fn
+	}
+}
+
+// ErrorMapper converts database errors to apperror.AppError.
+type ErrorMapper struct {
+	systemCode string
+	config     *ErrorMapperConfig
+}
+
+func NewErrorMapper(systemCode string, options ...ErrorMapperOption) *ErrorMapper {
+	config := &ErrorMapperConfig{
+		constraints: make(map[string]func(*Error) *apperror.AppError),
+	}
+
+	for _, o := range options {
+		o(config)
+	}
+
+	return &ErrorMapper{
+		systemCode: systemCode,
+		config:     config,
+	}
+}
+
+// Map returns the application error for err: pgx.ErrNoRows becomes not found,
+// unique violations become conflicts, foreign key violations become failed conditions
+// and not-null or check violations become validation errors. Other errors are returned as is.
+func (m *ErrorMapper) Map(err error) error {
+	classified := ClassifyError(err)
+	if classified == nil {
+		return nil
+	}
+
+	if fn, ok := m.config.constraints[classified.Constraint]; ok && classified.Constraint != "" {
+		return fn(classified)
+	}
+
+	options := []apperror.Option{apperror.WithErr(classified)}
+
+	switch classified.Kind {
+	case ErrorKindNoRows:
+		return apperror.NewNotFoundError(m.systemCode, append(options, apperror.WithMessage("not found"))...)
+	case ErrorKindUniqueViolation:
+		return apperror.NewConflictError(m.systemCode, append(options, apperror.WithMessage("already exists"))...)
+	case ErrorKindForeignKeyViolation:
+		return apperror.NewConditionFailedError(
+			m.systemCode,
+			append(options, apperror.WithMessage("referenced entity does not exist"))...,
+		)
+	case ErrorKindNotNullViolation, ErrorKindCheckViolation:
+		appErr := apperror.NewValidationError(m.systemCode, append(options, apperror.WithMessage("invalid value"))...)
+		if classified.Column != "" {
+			appErr.WithFields(apperror.ErrorFields{classified.Column: classified.Message})
+		}
+
+		return appErr
+	default:
+		return err
+	}
+}
@@ -0,0 +1,65 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Kazzess/libraries/apperror"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestClassifyError(t *testing.T) {
+	require.Nil(t, ClassifyError(nil))
+
+	uniqueErr := fmt.Errorf("create user: %w", &PgError{
+		Code:           pgerrcode.UniqueViolation,
+		Message:        "duplicate key value violates unique constraint",
+		TableName:      "users",
+		ConstraintName: "users_email_key",
+	})
+
+	classified := ClassifyError(uniqueErr)
+	require.Equal(t, ErrorKindUniqueViolation, classified.Kind)
+	require.Equal(t, "users", classified.Table)
+	require.Equal(t, "users_email_key", classified.Constraint)
+	require.ErrorIs(t, classified, uniqueErr)
+
+	require.Equal(t, ErrorKindForeignKeyViolation, ClassifyError(&PgError{Code: pgerrcode.ForeignKeyViolation}).Kind)
+	require.Equal(t, ErrorKindDeadlock, ClassifyError(&PgError{Code: pgerrcode.DeadlockDetected}).Kind)
+	require.Equal(t, ErrorKindConnection, ClassifyError(&PgError{Code: pgerrcode.AdminShutdown}).Kind)
+	require.Equal(t, ErrorKindNoRows, ClassifyError(fmt.Errorf("get user: %w", pgx.ErrNoRows)).Kind)
+	require.Equal(t, ErrorKindQueryCanceled, ClassifyError(context.DeadlineExceeded).Kind)
+	require.Equal(t, ErrorKindUnknown, ClassifyError(errors.New("boom")).Kind)
+	require.True(t, IsErrorKind(&PgError{Code: pgerrcode.CheckViolation}, ErrorKindCheckViolation))
+}
+
+func TestErrorMapper_Map(t *testing.T) {
+	mapper := NewErrorMapper("US", WithConstraintError("users_email_key", func(err *Error) *apperror.AppError {
+		return apperror.NewValidationError("US", apperror.WithErr(err), apperror.WithFields(apperror.ErrorFields{"email": "taken"}))
+	}))
+
+	require.NoError(t, mapper.Map(nil))
+
+	var appErr *apperror.AppError
+
+	require.ErrorAs(t, mapper.Map(pgx.ErrNoRows), &appErr)
+	require.Equal(t, codes.NotFound, appErr.GRPCStatus().Code())
+
+	require.ErrorAs(t, mapper.Map(&PgError{Code: pgerrcode.UniqueViolation, ConstraintName: "orders_pkey"}), &appErr)
+	require.Equal(t, codes.AlreadyExists, appErr.GRPCStatus().Code())
+
+	require.ErrorAs(t, mapper.Map(&PgError{Code: pgerrcode.ForeignKeyViolation}), &appErr)
+	require.Equal(t, codes.FailedPrecondition, appErr.GRPCStatus().Code())
+
+	require.ErrorAs(t, mapper.Map(&PgError{Code: pgerrcode.UniqueViolation, ConstraintName: "users_email_key"}), &appErr)
+	require.Equal(t, codes.InvalidArgument, appErr.GRPCStatus().Code())
+	require.Equal(t, "taken", appErr.Fields["email"])
+
+	otherErr := errors.New("boom")
+	require.Equal(t, otherErr, mapper.Map(otherErr))
+}
@@ -0,0 +1,103 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const defaultDedupTable = "nats_processed_messages"
+
+// DedupStore keeps IDs of handled messages in a table. It implements the DedupStore
+// of the nats module, so duplicates are detected by all instances. Expired rows are
+// replaced on Claim and removed by DeleteExpired.
+type DedupStore struct {
+	db    Querier
+	table string
+}
+
+// NewDedupStore returns the store using the table, "nats_processed_messages" if empty.
+// The name may be schema qualified.
+func NewDedupStore(db Querier, table string) *DedupStore {
+	if table == "" {
+		table = defaultDedupTable
+	}
+
+	return &DedupStore{db: db, table: tableIdentifier(table).Sanitize()}
+}
+
+// Schema returns the DDL of the table. Add it to migrations or call CreateTable.
+func (s *DedupStore) Schema() string {
+	return `CREATE TABLE IF NOT EXISTS ` + s.table + ` (
+	id TEXT PRIMARY KEY,
+	done BOOLEAN NOT NULL DEFAULT false,
+	expires_at TIMESTAMPTZ NOT NULL
+);`
+}
+
+func (s *DedupStore) CreateTable(ctx context.Context) error {
+	if _, err := s.db.Exec(ctx, s.Schema()); err != nil {
+		return fmt.Errorf("failed to create dedup table due to error: %w", err)
+	}
+
+	return nil
+}
+
+// Claim inserts the ID in progress and reports true if it isn't recorded or expired.
+// Otherwise it reports false and whether the message is handled.
+func (s *DedupStore) Claim(ctx context.Context, id string, ttl time.Duration) (bool, bool, error) {
+	var claimed, done bool
+
+	err := s.db.QueryRow(
+		ctx,
+		`WITH claimed AS (
+			INSERT INTO `+s.table+` AS t (id, done, expires_at) VALUES ($1, false, now() + $2 * interval '1 millisecond')
+			ON CONFLICT (id) DO UPDATE SET done = false, expires_at = EXCLUDED.expires_at WHERE t.expires_at <= now()
+			RETURNING id
+		)
+		SELECT
+			EXISTS (SELECT 1 FROM claimed),
+			COALESCE((SELECT done FROM `+s.table+` WHERE id = $1 AND expires_at > now()), false)`,
+		id,
+		ttl.Milliseconds(),
+	).Scan(&claimed, &done)
+	if err != nil {
+		return false, false, ErrScan(err)
+	}
+
+	return claimed, !claimed && done, nil
+}
+
+// Complete records the ID handled for the ttl.
+func (s *DedupStore) Complete(ctx context.Context, id string, ttl time.Duration) error {
+	_, err := s.db.Exec(
+		ctx,
+		`INSERT INTO `+s.table+` (id, done, expires_at) VALUES ($1, true, now() + $2 * interval '1 millisecond')
+		ON CONFLICT (id) DO UPDATE SET done = true, expires_at = EXCLUDED.expires_at`,
+		id,
+		ttl.Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete message id due to error: %w", err)
+	}
+
+	return nil
+}
+
+func (s *DedupStore) Remove(ctx context.Context, id string) error {
+	if _, err := s.db.Exec(ctx, `DELETE FROM `+s.table+` WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to remove message id due to error: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes expired IDs, call it periodically to keep the table small.
+func (s *DedupStore) DeleteExpired(ctx context.Context) (int64, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM `+s.table+` WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired message ids due to error: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,74 @@
+package psql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+// testDedupDB returns the row values for QueryRow and records the statements.
+type testDedupDB struct {
+	Querier
+	row  []bool
+	sql  []string
+	args [][]any
+}
+
+func (db *testDedupDB) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	db.sql = append(db.sql, sql)
+	db.args = append(db.args, args)
+
+	return testDedupRow(db.row)
+}
+
+func (db *testDedupDB) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	db.sql = append(db.sql, sql)
+	db.args = append(db.args, args)
+
+	return pgconn.NewCommandTag("DELETE 1"), nil
+}
+
+type testDedupRow []bool
+
+func (r testDedupRow) Scan(dest ...any) error {
+	for i, d := range dest {
+		*d.(*bool) = r[i]
+	}
+
+	return nil
+}
+
+func TestDedupStore(t *testing.T) {
+	ctx := context.Background()
+	db := &testDedupDB{}
+
+	store := NewDedupStore(db, "events.processed")
+	require.Contains(t, store.Schema(), `"events"."processed"`)
+
+	db.row = []bool{true, true}
+
+	claimed, done, err := store.Claim(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	require.False(t, done, "claimed ID is in progress")
+	require.Equal(t, []any{"a", int64(60000)}, db.args[0])
+
+	db.row = []bool{false, true}
+
+	claimed, done, err = store.Claim(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	require.False(t, claimed)
+	require.True(t, done)
+
+	require.NoError(t, store.Complete(ctx, "a", time.Hour))
+	require.Contains(t, db.sql[2], "SET done = true")
+	require.Equal(t, []any{"a", int64(3600000)}, db.args[2])
+
+	deleted, err := store.DeleteExpired(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, deleted)
+}
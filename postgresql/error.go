@@ -2,26 +2,48 @@ package psql
 
 import "fmt"
 
+// ConnectionError is returned by NewClient when the database is unreachable after all attempts.
+type ConnectionError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("unable to connect to PostgreSQL after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
 func ErrCommit(err error) error {
-	return fmt.Errorf("failed to commit Tx due to error: %v", err)
+	return fmt.Errorf("failed to commit Tx due to error: %w", err)
 }
 
 func ErrRollback(err error) error {
-	return fmt.Errorf("failed to rollback Tx due to error: %v", err)
+	return fmt.Errorf("failed to rollback Tx due to error: %w", err)
 }
 
 func ErrCreateTx(err error) error {
-	return fmt.Errorf("failed to create Tx due to error: %v", err)
+	return fmt.Errorf("failed to create Tx due to error: %w", err)
 }
 
 func ErrCreateQuery(err error) error {
-	return fmt.Errorf("failed to create SQL Query due to error: %v", err)
+	return fmt.Errorf("failed to create SQL Query due to error: %w", err)
 }
 
 func ErrScan(err error) error {
-	return fmt.Errorf("failed to scan due to error: %v", err)
+	return fmt.Errorf("failed to scan due to error: %w", err)
 }
 
 func ErrDoQuery(err error) error {
-	return fmt.Errorf("failed to query due to error: %v", err)
+	return fmt.Errorf("failed to query due to error: %w", err)
+}
+
+func ErrCopyFrom(err error) error {
+	return fmt.Errorf("failed to copy rows due to error: %w", err)
+}
+
+func ErrExecBatch(err error) error {
+	return fmt.Errorf("failed to execute batch due to error: %w", err)
 }
@@ -0,0 +1,213 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/Kazzess/libraries/logging"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	defaultListenerName     = "postgres_listener"
+	defaultListenerMinDelay = 100 * time.Millisecond
+	defaultListenerMaxDelay = 10 * time.Second
+)
+
+type Notification = pgconn.Notification
+
+type NotificationHandler func(ctx context.Context, notification *Notification) error
+
+type ListenerConfig struct {
+	minDelay time.Duration
+	maxDelay time.Duration
+	health   struct {
+		checker HealthChecker
+		name    string
+	}
+}
+
+type ListenerOption func(*ListenerConfig)
+
+// WithListenerHealthChecker reports whether the listener is connected under the name,
+// "postgres_listener" by default.
+func WithListenerHealthChecker(name string, hc HealthChecker) ListenerOption {
+	return func(cfg *ListenerConfig) {
+		cfg.health.checker = hc
+		cfg.health.name = name
+	}
+}
+
+// WithListenerReconnectDelay sets the exponential backoff bounds for reconnects.
+func WithListenerReconnectDelay(minDelay, maxDelay time.Duration) ListenerOption {
+	return func(cfg *ListenerConfig) {
+		cfg.minDelay = minDelay
+		cfg.maxDelay = maxDelay
+	}
+}
+
+// Listener receives notifications sent by NOTIFY on a dedicated connection.
+type Listener struct {
+	client   *Client
+	config   *ListenerConfig
+	mu       sync.RWMutex
+	handlers map[string]NotificationHandler
+}
+
+func (c *Client) NewListener(options ...ListenerOption) *Listener {
+	config := &ListenerConfig{
+		minDelay: defaultListenerMinDelay,
+		maxDelay: defaultListenerMaxDelay,
+	}
+
+	for _, o := range options {
+		o(config)
+	}
+
+	if config.health.checker != nil && config.health.name == "" {
+		config.health.name = defaultListenerName
+	}
+
+	return &Listener{
+		client:   c,
+		config:   config,
+		handlers: make(map[string]NotificationHandler),
+	}
+}
+
+// Handle registers the handler for the channel. Channels registered after Run are
+// listened after the next reconnect.
+func (l *Listener) Handle(channel string, handler NotificationHandler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.handlers[channel] = handler
+}
+
+// Run listens until ctx is done. Connection loss is retried with backoff and all
+// channels are listened again; notifications sent while disconnected are lost,
+// so handlers should resync state on reconnect if it matters.
+func (l *Listener) Run(ctx context.Context) error {
+	for attempt := 0; ; attempt++ {
+		connected, err := l.listen(ctx)
+		if ctx.Err() != nil {
+			l.setStatus(false)
+			return nil
+		}
+
+		l.setStatus(false)
+
+		if connected {
+			attempt = 0
+		}
+
+		delay := listenerDelay(attempt, l.config.minDelay, l.config.maxDelay)
+
+		logging.WithAttrs(ctx, logging.ErrAttr(err), logging.DurationAttr("delay", delay)).
+			Warn("postgres listener disconnected, reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// listen holds the connection until an error. connected reports whether LISTEN succeeded.
+func (l *Listener) listen(ctx context.Context) (connected bool, err error) {
+	pooled, err := l.client.Pool.Acquire(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire listener connection due to error: %w", err)
+	}
+
+	// The connection keeps LISTEN state, so it is taken out of the pool.
+	conn := pooled.Hijack()
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	l.mu.RLock()
+	channels := make([]string, 0, len(l.handlers))
+	for channel := range l.handlers {
+		channels = append(channels, channel)
+	}
+	l.mu.RUnlock()
+
+	for _, channel := range channels {
+		if _, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return false, fmt.Errorf("failed to listen channel %s due to error: %w", channel, err)
+		}
+	}
+
+	l.setStatus(true)
+
+	for {
+		notification, waitErr := conn.WaitForNotification(ctx)
+		if waitErr != nil {
+			return true, waitErr
+		}
+
+		l.dispatch(ctx, notification)
+	}
+}
+
+func (l *Listener) dispatch(ctx context.Context, notification *Notification) {
+	l.mu.RLock()
+	handler, ok := l.handlers[notification.Channel]
+	l.mu.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	start := time.Now()
+
+	err := safeHandle(ctx, handler, notification)
+	observeNotification(notification.Channel, err, time.Since(start))
+
+	if err != nil {
+		logging.WithAttrs(
+			ctx,
+			logging.StringAttr("channel", notification.Channel),
+			logging.ErrAttr(err),
+		).Error("failed to handle postgres notification")
+	}
+}
+
+func safeHandle(ctx context.Context, handler NotificationHandler, notification *Notification) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("notification handler panic: %v\n%s", p, debug.Stack())
+		}
+	}()
+
+	return handler(ctx, notification)
+}
+
+func (l *Listener) setStatus(connected bool) {
+	if l.config.health.checker != nil {
+		l.config.health.checker.SetStatus(l.config.health.name, connected)
+	}
+}
+
+// Notify sends the notification with pg_notify. Inside WithTx it is delivered on commit.
+func (c *Client) Notify(ctx context.Context, channel, payload string) error {
+	if _, err := c.Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload); err != nil {
+		return ErrDoQuery(err)
+	}
+
+	return nil
+}
+
+func listenerDelay(attempt int, minDelay, maxDelay time.Duration) time.Duration {
+	delay := float64(minDelay) * math.Pow(retryBackoffFactor, float64(attempt))
+	if delay > float64(maxDelay) {
+		return maxDelay
+	}
+
+	return time.Duration(delay)
+}
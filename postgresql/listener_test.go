@@ -0,0 +1,59 @@
+package psql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testHealthChecker struct {
+	statuses map[string]bool
+}
+
+func (hc *testHealthChecker) SetStatus(name string, status bool) {
+	hc.statuses[name] = status
+}
+
+func TestListener_Dispatch(t *testing.T) {
+	hc := &testHealthChecker{statuses: map[string]bool{}}
+	listener := (&Client{}).NewListener(WithListenerHealthChecker("", hc))
+
+	var received []string
+
+	listener.Handle("cache_invalidation", func(ctx context.Context, n *Notification) error {
+		received = append(received, n.Payload)
+		return nil
+	})
+	listener.Handle("broken", func(ctx context.Context, n *Notification) error {
+		panic("boom")
+	})
+
+	ctx := context.Background()
+
+	listener.dispatch(ctx, &Notification{Channel: "cache_invalidation", Payload: "users:1"})
+	listener.dispatch(ctx, &Notification{Channel: "unknown", Payload: "ignored"})
+	require.NotPanics(t, func() {
+		listener.dispatch(ctx, &Notification{Channel: "broken"})
+	})
+
+	require.Equal(t, []string{"users:1"}, received)
+
+	listener.setStatus(true)
+	require.True(t, hc.statuses[defaultListenerName])
+}
+
+func TestSafeHandle(t *testing.T) {
+	err := safeHandle(context.Background(), func(ctx context.Context, n *Notification) error {
+		panic("boom")
+	}, &Notification{})
+
+	require.ErrorContains(t, err, "notification handler panic: boom")
+}
+
+func TestListenerDelay(t *testing.T) {
+	require.Equal(t, 100*time.Millisecond, listenerDelay(0, 100*time.Millisecond, time.Second))
+	require.Equal(t, 400*time.Millisecond, listenerDelay(2, 100*time.Millisecond, time.Second))
+	require.Equal(t, time.Second, listenerDelay(10, 100*time.Millisecond, time.Second))
+}
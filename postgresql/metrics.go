@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/Kazzess/libraries/metrics"
@@ -14,55 +15,191 @@ var (
 	postgresAvailability = metrics.NewGaugeVec(
 		metrics.GaugeOpts{
 			Name: "postgres_availability",
-			Help: "Indicates the availability of PostgreSQL connection (1 for available, 0 for unavailable)",
+			Help: "Indicates the availability of PostgreSQL nodes (1 for available, 0 for unavailable)",
+		},
+		[]string{"host", "database", "role"},
+	)
+
+	replicationLagMs = metrics.NewGaugeVec(
+		metrics.GaugeOpts{
+			Name: "postgres_replication_lag_ms",
+			Help: "The replay lag of PostgreSQL read replicas (milliseconds)",
+		},
+		[]string{"host", "database"},
+	)
+
+	// queryTimeMs is a histogram that measures the time of queries by name (milliseconds).
+	queryTimeMs = metrics.NewHistogramVec(
+		metrics.HistogramOpts{
+			Name:    "postgres_query_time_ms",
+			Help:    "The time of PostgreSQL queries by query name (milliseconds)",
+			Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000},
+		},
+		[]string{"query", "operation", "is_err"},
+	)
+
+	// notificationProcessingTimeMs is a histogram that measures the time of handling notifications (milliseconds).
+	notificationProcessingTimeMs = metrics.NewHistogramVec(
+		metrics.HistogramOpts{
+			Name:    "postgres_notification_processing_time_ms",
+			Help:    "The time of handling PostgreSQL notifications by channel (milliseconds)",
+			Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000},
+		},
+		[]string{"channel", "is_err"},
+	)
+
+	poolAcquiredConns = metrics.NewGaugeVec(
+		metrics.GaugeOpts{
+			Name: "postgres_pool_acquired_conns",
+			Help: "The number of currently acquired connections in the pool",
+		},
+		[]string{"host", "database"},
+	)
+
+	poolIdleConns = metrics.NewGaugeVec(
+		metrics.GaugeOpts{
+			Name: "postgres_pool_idle_conns",
+			Help: "The number of currently idle connections in the pool",
+		},
+		[]string{"host", "database"},
+	)
+
+	poolTotalConns = metrics.NewGaugeVec(
+		metrics.GaugeOpts{
+			Name: "postgres_pool_total_conns",
+			Help: "The total number of connections in the pool including constructing ones",
+		},
+		[]string{"host", "database"},
+	)
+
+	poolMaxConns = metrics.NewGaugeVec(
+		metrics.GaugeOpts{
+			Name: "postgres_pool_max_conns",
+			Help: "The maximum size of the pool",
+		},
+		[]string{"host", "database"},
+	)
+
+	poolEmptyAcquiresTotal = metrics.NewCounterVec(
+		metrics.CounterOpts{
+			Name: "postgres_pool_empty_acquire_total",
+			Help: "The number of acquires that waited for a connection because the pool was empty",
+		},
+		[]string{"host", "database"},
+	)
+
+	poolAcquireDurationMsTotal = metrics.NewCounterVec(
+		metrics.CounterOpts{
+			Name: "postgres_pool_acquire_duration_ms_total",
+			Help: "The total duration of all successful acquires from the pool (milliseconds)",
 		},
 		[]string{"host", "database"},
 	)
 )
 
-func checkPostgresAvailability(ctx context.Context, pool *pgxpool.Pool, cfg *Config) error {
-	u, err := url.Parse(cfg.dsn)
-	if err != nil {
-		return err
+func observeQuery(name, operation string, err error, duration time.Duration) {
+	queryTimeMs.
+		WithLabelValues(name, operation, strconv.FormatBool(err != nil)).
+		Observe(float64(duration.Microseconds()) / 1000)
+}
+
+func observeNotification(channel string, err error, duration time.Duration) {
+	notificationProcessingTimeMs.
+		WithLabelValues(channel, strconv.FormatBool(err != nil)).
+		Observe(float64(duration.Microseconds()) / 1000)
+}
+
+// poolStats exports Pool.Stat() values. Cumulative values are exported as counters
+// incremented by their change since the previous observe.
+type poolStats struct {
+	emptyAcquires   int64
+	acquireDuration time.Duration
+}
+
+func (s *poolStats) observe(pool *pgxpool.Pool, labels availability) {
+	stat := pool.Stat()
+
+	poolAcquiredConns.WithLabelValues(labels.host, labels.database).Set(float64(stat.AcquiredConns()))
+	poolIdleConns.WithLabelValues(labels.host, labels.database).Set(float64(stat.IdleConns()))
+	poolTotalConns.WithLabelValues(labels.host, labels.database).Set(float64(stat.TotalConns()))
+	poolMaxConns.WithLabelValues(labels.host, labels.database).Set(float64(stat.MaxConns()))
+
+	if delta := stat.EmptyAcquireCount() - s.emptyAcquires; delta > 0 {
+		poolEmptyAcquiresTotal.WithLabelValues(labels.host, labels.database).Add(float64(delta))
 	}
 
-	host := u.Hostname()
+	if delta := stat.AcquireDuration() - s.acquireDuration; delta > 0 {
+		poolAcquireDurationMsTotal.WithLabelValues(labels.host, labels.database).
+			Add(float64(delta.Microseconds()) / 1000)
+	}
+
+	s.emptyAcquires = stat.EmptyAcquireCount()
+	s.acquireDuration = stat.AcquireDuration()
+}
+
+const (
+	rolePrimary = "primary"
+	roleReplica = "replica"
+)
+
+type availability struct {
+	host     string
+	database string
+	role     string
+}
+
+func availabilityLabels(dsn, role string) (availability, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return availability{}, err
+	}
 
 	pathSegments := u.Path
 	if len(pathSegments) > 1 {
 		pathSegments = pathSegments[1:]
 	}
 
-	postgresAvailability.WithLabelValues(host, pathSegments).Set(0)
+	return availability{host: u.Hostname(), database: pathSegments, role: role}, nil
+}
 
+func checkPostgresAvailability(ctx context.Context, pool *pgxpool.Pool, cfg *Config, labels availability) {
 	go func() {
 		ticker := time.NewTicker(cfg.health.intervalCheck)
 		defer ticker.Stop()
 
+		var stats poolStats
+
 		for {
 			select {
 			case <-ticker.C:
 				pingErr := pool.Ping(ctx)
 				if pingErr != nil {
 					log.Printf("Failed to ping PostgreSQL due to error: %v\n", pingErr)
-
-					postgresAvailability.WithLabelValues(host, pathSegments).Set(0)
-
-					if cfg.health.checker != nil {
-						cfg.health.checker.SetStatus(cfg.health.name, false)
-					}
-				} else {
-					postgresAvailability.WithLabelValues(host, pathSegments).Set(1)
-
-					if cfg.health.checker != nil {
-						cfg.health.checker.SetStatus(cfg.health.name, true)
-					}
 				}
+
+				setPostgresAvailability(cfg, labels, pingErr == nil)
+				stats.observe(pool, labels)
 			case <-ctx.Done():
 				return
 			}
 		}
 	}()
+}
+
+// setPostgresAvailability reports the connection state to the gauge and the health checker.
+func setPostgresAvailability(cfg *Config, labels availability, available bool) {
+	setNodeAvailability(labels, available)
+
+	if cfg.health.checker != nil {
+		cfg.health.checker.SetStatus(cfg.health.name, available)
+	}
+}
+
+func setNodeAvailability(labels availability, available bool) {
+	var value float64
+	if available {
+		value = 1
+	}
 
-	return nil
+	postgresAvailability.WithLabelValues(labels.host, labels.database, labels.role).Set(value)
 }
@@ -0,0 +1,373 @@
+package psql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Kazzess/libraries/logging"
+	"github.com/jackc/pgx/v5"
+)
+
+const defaultMigrationsTable = "schema_migrations"
+
+const (
+	MigrationUp   = "up"
+	MigrationDown = "down"
+)
+
+var (
+	ErrMigrationChecksum  = errors.New("applied migration differs from the migration file")
+	ErrMigrationMissing   = errors.New("applied migration has no migration file")
+	ErrMigrationNoDown    = errors.New("migration has no down file")
+	ErrMigrationDuplicate = errors.New("duplicate migration version")
+)
+
+// migrationFileRegexp matches "<version>_<name>.up.sql" and "<version>_<name>.down.sql".
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a pair of up and down SQL files with the same version.
+type Migration struct {
+	Version  uint64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStep is a migration applied (or planned in the dry-run mode) by Migrate.
+type MigrationStep struct {
+	Version   uint64
+	Name      string
+	Direction string
+}
+
+type appliedMigration struct {
+	version  uint64
+	checksum string
+}
+
+type MigrationConfig struct {
+	fsys          fs.FS
+	dir           string
+	table         string
+	targetVersion *uint64
+	dryRun        bool
+}
+
+type MigrationOption func(*MigrationConfig)
+
+// WithMigrationsTable sets the table with applied versions, "schema_migrations" by default.
+// The name may be schema qualified.
+func WithMigrationsTable(table string) MigrationOption {
+	return func(cfg *MigrationConfig) {
+		cfg.table = table
+	}
+}
+
+// WithTargetVersion migrates up or down to the version instead of the latest one.
+func WithTargetVersion(version uint64) MigrationOption {
+	return func(cfg *MigrationConfig) {
+		cfg.targetVersion = &version
+	}
+}
+
+// WithDryRun only logs and returns the steps without executing them. It makes no changes,
+// even the migrations table is not created.
+func WithDryRun(dryRun bool) MigrationOption {
+	return func(cfg *MigrationConfig) {
+		cfg.dryRun = dryRun
+	}
+}
+
+// WithMigrations runs migrations from the directory of fsys in NewClient after the
+// connection is established. Use embed.FS to ship migrations with the binary.
+func WithMigrations(fsys fs.FS, dir string, options ...MigrationOption) Option {
+	return func(cfg *Config) {
+		cfg.migrations = newMigrationConfig(fsys, dir, options...)
+	}
+}
+
+func newMigrationConfig(fsys fs.FS, dir string, options ...MigrationOption) *MigrationConfig {
+	config := &MigrationConfig{
+		fsys:  fsys,
+		dir:   dir,
+		table: defaultMigrationsTable,
+	}
+
+	for _, o := range options {
+		o(config)
+	}
+
+	return config
+}
+
+// Migrate applies migrations from the directory of fsys. Only one replica migrates at
+// a time: the others wait on the advisory lock and then find nothing to apply.
+// Every migration runs in its own transaction.
+func (c *Client) Migrate(ctx context.Context, fsys fs.FS, dir string, options ...MigrationOption) ([]MigrationStep, error) {
+	return c.migrate(ctx, newMigrationConfig(fsys, dir, options...))
+}
+
+func (c *Client) migrate(ctx context.Context, cfg *MigrationConfig) ([]MigrationStep, error) {
+	migrations, err := LoadMigrations(cfg.fsys, cfg.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := c.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection for migrations due to error: %w", err)
+	}
+	defer conn.Release()
+
+	lockID := migrationsLockID(cfg.table)
+
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return nil, fmt.Errorf("failed to take migrations lock due to error: %w", err)
+	}
+
+	defer func() {
+		// The lock is released with the session anyway, so the error is only logged.
+		if _, unlockErr := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockID); unlockErr != nil {
+			logging.WithAttrs(ctx, logging.ErrAttr(unlockErr)).Error("failed to release migrations lock")
+		}
+	}()
+
+	table := tableIdentifier(cfg.table).Sanitize()
+
+	applied, err := appliedMigrations(ctx, conn.Conn(), table, cfg.dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := planMigrations(migrations, applied, cfg.targetVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	for _, step := range steps {
+		log := logging.WithAttrs(
+			ctx,
+			logging.Uint64Attr("version", step.Version),
+			logging.StringAttr("name", step.Name),
+			logging.StringAttr("direction", step.Direction),
+			logging.BoolAttr("dry_run", cfg.dryRun),
+		)
+
+		if cfg.dryRun {
+			log.Info("migration planned")
+			continue
+		}
+
+		if err = applyMigration(ctx, conn.Conn(), table, byVersion[step.Version], step.Direction); err != nil {
+			return nil, fmt.Errorf("migration %d_%s %s: %w", step.Version, step.Name, step.Direction, err)
+		}
+
+		log.Info("migration applied")
+	}
+
+	return steps, nil
+}
+
+// appliedMigrations returns the migrations recorded in the table, creating it if it doesn't
+// exist. Dry run makes no changes, so the missing table means no migrations are applied.
+func appliedMigrations(ctx context.Context, conn *pgx.Conn, table string, dryRun bool) ([]appliedMigration, error) {
+	if dryRun {
+		var exists bool
+		if err := conn.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists); err != nil {
+			return nil, ErrDoQuery(err)
+		}
+
+		if !exists {
+			return nil, nil
+		}
+	} else {
+		_, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+table+` (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create migrations table due to error: %w", err)
+		}
+	}
+
+	rows, err := conn.Query(ctx, `SELECT version, checksum FROM `+table+` ORDER BY version`)
+	if err != nil {
+		return nil, ErrDoQuery(err)
+	}
+
+	applied, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (appliedMigration, error) {
+		var m appliedMigration
+		err := row.Scan(&m.version, &m.checksum)
+
+		return m, err
+	})
+	if err != nil {
+		return nil, ErrScan(err)
+	}
+
+	return applied, nil
+}
+
+func applyMigration(ctx context.Context, conn *pgx.Conn, table string, m Migration, direction string) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if direction == MigrationDown {
+			if _, err := tx.Exec(ctx, m.Down); err != nil {
+				return err
+			}
+
+			_, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE version = $1`, m.Version)
+
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, m.Up); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(
+			ctx,
+			`INSERT INTO `+table+` (version, name, checksum) VALUES ($1, $2, $3)`,
+			m.Version, m.Name, m.Checksum,
+		)
+
+		return err
+	})
+}
+
+// LoadMigrations reads migrations from the directory of fsys sorted by version.
+// Files not matching "<version>_<name>.(up|down).sql" are ignored.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations dir due to error: %w", err)
+	}
+
+	byVersion := make(map[uint64]*Migration)
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		matches := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		version, parseErr := strconv.ParseUint(matches[1], 10, 64)
+		if parseErr != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), parseErr)
+		}
+
+		data, readErr := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read migration %s due to error: %w", entry.Name(), readErr)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		} else if m.Name != matches[2] {
+			return nil, fmt.Errorf("%w %d: %s and %s", ErrMigrationDuplicate, version, m.Name, matches[2])
+		}
+
+		if matches[3] == MigrationUp {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, m := range byVersion {
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// planMigrations validates applied migrations against the files and returns the steps
+// to reach the target version, the latest one if target is nil.
+func planMigrations(migrations []Migration, applied []appliedMigration, target *uint64) ([]MigrationStep, error) {
+	byVersion := make(map[uint64]Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	isApplied := make(map[uint64]bool, len(applied))
+
+	for _, a := range applied {
+		m, ok := byVersion[a.version]
+		if !ok {
+			return nil, fmt.Errorf("%w: version %d", ErrMigrationMissing, a.version)
+		}
+
+		if m.Checksum != a.checksum {
+			return nil, fmt.Errorf("%w: version %d", ErrMigrationChecksum, a.version)
+		}
+
+		isApplied[a.version] = true
+	}
+
+	var steps []MigrationStep
+
+	// Versions above the target are rolled back before the missing ones are applied.
+	for i := len(applied) - 1; target != nil && i >= 0; i-- {
+		m := byVersion[applied[i].version]
+		if m.Version <= *target {
+			continue
+		}
+
+		if strings.TrimSpace(m.Down) == "" {
+			return nil, fmt.Errorf("%w: version %d", ErrMigrationNoDown, m.Version)
+		}
+
+		steps = append(steps, MigrationStep{Version: m.Version, Name: m.Name, Direction: MigrationDown})
+	}
+
+	for _, m := range migrations {
+		if isApplied[m.Version] || (target != nil && m.Version > *target) || m.Up == "" {
+			continue
+		}
+
+		steps = append(steps, MigrationStep{Version: m.Version, Name: m.Name, Direction: MigrationUp})
+	}
+
+	return steps, nil
+}
+
+// migrationsLockID derives the advisory lock key from the table name, so services
+// sharing a database but using different tables do not block each other.
+func migrationsLockID(table string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("psql.migrations." + table))
+
+	return int64(h.Sum64())
+}
@@ -0,0 +1,95 @@
+package psql
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testMigrations = fstest.MapFS{
+	"migrations/0001_users.up.sql":     {Data: []byte("CREATE TABLE users (id BIGINT PRIMARY KEY);")},
+	"migrations/0001_users.down.sql":   {Data: []byte("DROP TABLE users;")},
+	"migrations/0002_orders.up.sql":    {Data: []byte("CREATE TABLE orders (id BIGINT PRIMARY KEY);")},
+	"migrations/0002_orders.down.sql":  {Data: []byte("DROP TABLE orders;")},
+	"migrations/0010_indexes.up.sql":   {Data: []byte("CREATE INDEX ON orders (id);")},
+	"migrations/README.md":             {Data: []byte("ignored")},
+	"migrations/0003_seed.sql.example": {Data: []byte("ignored")},
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(testMigrations, "migrations")
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+
+	require.EqualValues(t, 1, migrations[0].Version)
+	require.Equal(t, "users", migrations[0].Name)
+	require.Equal(t, "DROP TABLE users;", migrations[0].Down)
+	require.EqualValues(t, 10, migrations[2].Version)
+	require.Empty(t, migrations[2].Down)
+	require.NotEqual(t, migrations[0].Checksum, migrations[1].Checksum)
+}
+
+func TestLoadMigrations_Duplicate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_users.up.sql":    {Data: []byte("SELECT 1;")},
+		"0001_accounts.up.sql": {Data: []byte("SELECT 1;")},
+	}
+
+	_, err := LoadMigrations(fsys, ".")
+	require.ErrorIs(t, err, ErrMigrationDuplicate)
+}
+
+func TestPlanMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(testMigrations, "migrations")
+	require.NoError(t, err)
+
+	applied := []appliedMigration{{version: 1, checksum: migrations[0].Checksum}}
+
+	steps, err := planMigrations(migrations, applied, nil)
+	require.NoError(t, err)
+	require.Equal(t, []MigrationStep{
+		{Version: 2, Name: "orders", Direction: MigrationUp},
+		{Version: 10, Name: "indexes", Direction: MigrationUp},
+	}, steps)
+
+	target := uint64(2)
+	steps, err = planMigrations(migrations, applied, &target)
+	require.NoError(t, err)
+	require.Equal(t, []MigrationStep{{Version: 2, Name: "orders", Direction: MigrationUp}}, steps)
+
+	applied = append(applied, appliedMigration{version: 2, checksum: migrations[1].Checksum})
+	target = 0
+	steps, err = planMigrations(migrations, applied, &target)
+	require.NoError(t, err)
+	require.Equal(t, []MigrationStep{
+		{Version: 2, Name: "orders", Direction: MigrationDown},
+		{Version: 1, Name: "users", Direction: MigrationDown},
+	}, steps)
+
+	// Version 2 is applied without version 1, e.g. after merging branches.
+	applied = []appliedMigration{{version: 2, checksum: migrations[1].Checksum}}
+	target = 1
+	steps, err = planMigrations(migrations, applied, &target)
+	require.NoError(t, err)
+	require.Equal(t, []MigrationStep{
+		{Version: 2, Name: "orders", Direction: MigrationDown},
+		{Version: 1, Name: "users", Direction: MigrationUp},
+	}, steps)
+}
+
+func TestPlanMigrations_Validation(t *testing.T) {
+	migrations, err := LoadMigrations(testMigrations, "migrations")
+	require.NoError(t, err)
+
+	_, err = planMigrations(migrations, []appliedMigration{{version: 1, checksum: "changed"}}, nil)
+	require.ErrorIs(t, err, ErrMigrationChecksum)
+
+	_, err = planMigrations(migrations, []appliedMigration{{version: 5, checksum: "unknown"}}, nil)
+	require.ErrorIs(t, err, ErrMigrationMissing)
+
+	target := uint64(2)
+	applied := []appliedMigration{{version: 10, checksum: migrations[2].Checksum}}
+	_, err = planMigrations(migrations, applied, &target)
+	require.ErrorIs(t, err, ErrMigrationNoDown)
+}
@@ -2,10 +2,13 @@ package psql
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/Kazzess/libraries/core/repeat"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -13,6 +16,8 @@ import (
 const (
 	defaultIntervalCheck = 10 * time.Second
 	defaultName          = "postgres"
+	defaultMinRetryDelay = 100 * time.Millisecond
+	retryBackoffFactor   = 2
 )
 
 type HealthChecker interface {
@@ -24,6 +29,9 @@ type Config struct {
 	dsn         string
 	maxAttempts int
 	maxDelay    time.Duration
+	lazy        bool
+	migrations  *MigrationConfig
+	replicas    replicasConfig
 	health      struct {
 		checker       HealthChecker
 		intervalCheck time.Duration
@@ -57,6 +65,14 @@ func WithIntervalCheck(interval time.Duration) Option {
 	}
 }
 
+// WithLazyConnect makes NewClient return the client without waiting for the connection.
+// The client keeps connecting in background and reports readiness to the health checker.
+func WithLazyConnect(lazy bool) Option {
+	return func(cfg *Config) {
+		cfg.lazy = lazy
+	}
+}
+
 func NewConfig(dsn string, maxAttempts int, maxDelay time.Duration, options ...Option) (*Config, error) {
 	cfg, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
@@ -85,14 +101,26 @@ func NewConfig(dsn string, maxAttempts int, maxDelay time.Duration, options ...O
 		config.health.intervalCheck = defaultIntervalCheck
 	}
 
+	if err = config.parseReplicas(); err != nil {
+		log.Printf("Unable to parse replica config: %v\n", err)
+		return nil, err
+	}
+
 	return config, nil
 }
 
 type Client struct {
 	*pgxpool.Pool
+	replicas    []*replica
+	balancer    Balancer
+	autoRouting bool
+	next        atomic.Uint64
 }
 
-// NewClient creates new postgres client.
+// NewClient creates new postgres client. It pings the database up to maxAttempts times with
+// exponential backoff capped by maxDelay and returns ConnectionError if all attempts fail.
+// Non-positive maxAttempts means retrying until ctx is done.
+// Migrations set by WithMigrations are applied before the client is reported as available.
 func NewClient(ctx context.Context, cfg *Config) (client *Client, err error) {
 	pool, configErr := pgxpool.NewWithConfig(ctx, cfg.pgxConfig)
 	if configErr != nil {
@@ -100,31 +128,111 @@ func NewClient(ctx context.Context, cfg *Config) (client *Client, err error) {
 		return nil, configErr
 	}
 
-	err = checkPostgresAvailability(ctx, pool, cfg)
+	client = &Client{
+		Pool:        pool,
+		balancer:    cfg.replicas.balancer,
+		autoRouting: cfg.replicas.autoRouting,
+	}
+
+	labels, err := availabilityLabels(cfg.dsn, rolePrimary)
 	if err != nil {
+		pool.Close()
 		return nil, err
 	}
 
-	err = DoWithAttempts(func() error {
-		pingErr := pool.Ping(ctx)
-		if pingErr != nil {
-			log.Printf("Failed to connect to postgres due to error %v... Going to do the next attempt\n", pingErr)
-			return pingErr
+	client.replicas, err = newReplicas(ctx, cfg)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	setPostgresAvailability(cfg, labels, false)
+
+	connect := func() error {
+		var attempts int
+
+		connectErr := repeat.Exec(ctx, func(ctx context.Context) error {
+			attempts++
+
+			pingErr := pool.Ping(ctx)
+			if pingErr != nil {
+				log.Printf("Failed to connect to postgres due to error %v... Going to do the next attempt\n", pingErr)
+				return pingErr
+			}
+
+			return nil
+		}, retryOptions(cfg.maxAttempts, cfg.maxDelay, &attempts)...)
+		if connectErr != nil {
+			return &ConnectionError{Attempts: attempts, Err: connectErr}
+		}
+
+		if cfg.migrations != nil {
+			if _, migrateErr := client.migrate(ctx, cfg.migrations); migrateErr != nil {
+				return fmt.Errorf("failed to apply migrations due to error: %w", migrateErr)
+			}
 		}
 
+		setPostgresAvailability(cfg, labels, true)
+
 		return nil
-	}, cfg.maxAttempts, cfg.maxDelay)
-	if err != nil {
-		log.Fatal("All attempts are exceeded. Unable to connect to PostgreSQL")
 	}
 
-	client = &Client{
-		Pool: pool,
+	if cfg.lazy {
+		// The checks start after the migrations, so the client isn't reported available before.
+		go func() {
+			if connectErr := connect(); connectErr != nil {
+				log.Printf("Unable to connect to PostgreSQL: %v\n", connectErr)
+				return
+			}
+
+			client.checkAvailability(ctx, cfg, labels)
+		}()
+
+		return client, nil
 	}
 
+	if err = connect(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	client.checkAvailability(ctx, cfg, labels)
+
 	return client, nil
 }
 
+func (c *Client) checkAvailability(ctx context.Context, cfg *Config, labels availability) {
+	checkPostgresAvailability(ctx, c.Pool, cfg, labels)
+
+	for _, r := range c.replicas {
+		checkReplicaAvailability(ctx, r, cfg)
+	}
+}
+
+// retryOptions returns exponential backoff from defaultMinRetryDelay up to maxDelay.
+// attempts is the number of attempts made, retrying stops without a wait after the last one.
+func retryOptions(maxAttempts int, maxDelay time.Duration, attempts *int) []repeat.OptionSetter {
+	minDelay := defaultMinRetryDelay
+	if maxDelay < minDelay {
+		minDelay = maxDelay
+	}
+
+	if maxAttempts <= 0 {
+		maxAttempts = -1
+	}
+
+	return []repeat.OptionSetter{
+		repeat.WithMaxRetries(maxAttempts),
+		repeat.WithErrorHandler(func(error) bool {
+			return maxAttempts == -1 || *attempts < maxAttempts
+		}),
+		repeat.WithMinTimeWait(minDelay),
+		repeat.WithMaxTimeWait(maxDelay),
+		repeat.WithBackoffFactor(retryBackoffFactor),
+	}
+}
+
+// Deprecated: DoWithAttempts does not observe the context, use repeat.Exec instead.
 func DoWithAttempts(fn func() error, maxAttempts int, delay time.Duration) error {
 	var err error
 
@@ -0,0 +1,229 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Kazzess/libraries/sfqb"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// NotFoundError is returned by QueryOne when the query returns no rows.
+// It unwraps to pgx.ErrNoRows, so ClassifyError and ErrorMapper treat it as not found.
+type NotFoundError struct {
+	Query string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("query %s returned no rows", e.Query)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return pgx.ErrNoRows
+}
+
+// IsNotFound reports whether err is NotFoundError or pgx.ErrNoRows.
+func IsNotFound(err error) bool {
+	return errors.Is(err, pgx.ErrNoRows)
+}
+
+// Page is a page of rows returned by QueryPage.
+type Page[T any] struct {
+	Items  []T
+	Total  int64
+	Limit  int
+	Offset int
+}
+
+// QueryOne scans the first row into T. Columns are mapped to fields by "db" tags
+// the same way as in BulkInsert. It returns NotFoundError if there are no rows.
+func QueryOne[T any](ctx context.Context, q Querier, sql string, args ...any) (T, error) {
+	var zero T
+
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return zero, ErrDoQuery(err)
+	}
+
+	item, err := pgx.CollectOneRow(rows, RowToStruct[T])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return zero, &NotFoundError{Query: queryName(ctx, sql)}
+		}
+
+		return zero, ErrScan(err)
+	}
+
+	return item, nil
+}
+
+// QueryAll scans all rows into a slice of T.
+func QueryAll[T any](ctx context.Context, q Querier, sql string, args ...any) ([]T, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, ErrDoQuery(err)
+	}
+
+	items, err := pgx.CollectRows(rows, RowToStruct[T])
+	if err != nil {
+		return nil, ErrScan(err)
+	}
+
+	return items, nil
+}
+
+// QueryPage wraps sql into a subquery and applies filters, search, sorting, limit and
+// offset of query to it, so filters may use any column returned by sql. Conditions of
+// query use "?" placeholders which are numbered after args. The total is counted
+// with the same filters and without limit and offset.
+func QueryPage[T any](ctx context.Context, q Querier, sql string, query sfqb.SFQB, args ...any) (Page[T], error) {
+	pageSQL, countSQL, pageArgs := pageQueries(sql, query, args)
+
+	items, err := QueryAll[T](ctx, q, pageSQL, pageArgs...)
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	var total int64
+	if err = q.QueryRow(ctx, countSQL, pageArgs...).Scan(&total); err != nil {
+		return Page[T]{}, ErrScan(err)
+	}
+
+	return Page[T]{
+		Items:  items,
+		Total:  total,
+		Limit:  query.Limit(),
+		Offset: query.Offset(),
+	}, nil
+}
+
+// likeEscaper escapes the LIKE wildcards, so the search term is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func pageQueries(sql string, query sfqb.SFQB, args []any) (pageSQL, countSQL string, pageArgs []any) {
+	var conditions []string
+
+	pageArgs = append(pageArgs, args...)
+
+	if where := query.Where(); where != "" {
+		conditions = append(conditions, "("+where+")")
+		pageArgs = append(pageArgs, query.Args()...)
+	}
+
+	if query.HasSearch() && query.Search().Term() != "" && len(query.Search().Fields()) > 0 {
+		term := "%" + likeEscaper.Replace(query.Search().Term()) + "%"
+
+		search := make([]string, 0, len(query.Search().Fields()))
+		for _, field := range query.Search().Fields() {
+			search = append(search, pgx.Identifier{field}.Sanitize()+`::text ILIKE ? ESCAPE '\'`)
+			pageArgs = append(pageArgs, term)
+		}
+
+		conditions = append(conditions, "("+strings.Join(search, " OR ")+")")
+	}
+
+	from := "FROM (" + sql + ") AS q"
+	if len(conditions) > 0 {
+		from += " WHERE " + rebindPlaceholders(strings.Join(conditions, " AND "), len(args))
+	}
+
+	pageSQL = "SELECT * " + from
+
+	if order := query.Order(); order != "" {
+		pageSQL += " ORDER BY " + order
+	}
+
+	if query.Limit() > 0 {
+		pageSQL += " LIMIT " + strconv.Itoa(query.Limit())
+	}
+
+	if query.Offset() > 0 {
+		pageSQL += " OFFSET " + strconv.Itoa(query.Offset())
+	}
+
+	return pageSQL, "SELECT count(*) " + from, pageArgs
+}
+
+// rebindPlaceholders replaces "?" outside of quotes by $n starting from offset+1.
+func rebindPlaceholders(sql string, offset int) string {
+	var (
+		b     strings.Builder
+		quote rune
+	)
+
+	for _, r := range sql {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			offset++
+			b.WriteString("$" + strconv.Itoa(offset))
+
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// RowToStruct is a pgx.RowToFunc mapping columns to fields of T by "db" tags.
+// Nested structs with "db" tags are filled from prefixed columns, embedded pointers
+// are allocated and NULL values require pointer or pgtype fields.
+func RowToStruct[T any](row pgx.CollectableRow) (T, error) {
+	var item T
+
+	v := reflect.ValueOf(&item).Elem()
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return item, ErrNotStruct
+	}
+
+	targets, err := scanTargets(v, row.FieldDescriptions())
+	if err != nil {
+		return item, err
+	}
+
+	return item, row.Scan(targets...)
+}
+
+func scanTargets(v reflect.Value, descriptions []pgconn.FieldDescription) ([]any, error) {
+	fields := structFields(v.Type())
+
+	byColumn := make(map[string][]int, len(fields))
+	for _, field := range fields {
+		byColumn[field.column] = field.index
+	}
+
+	targets := make([]any, 0, len(descriptions))
+
+	for _, description := range descriptions {
+		index, ok := byColumn[description.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, description.Name)
+		}
+
+		target, err := fieldAddr(v, index)
+		if err != nil {
+			return nil, err
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
@@ -0,0 +1,91 @@
+package psql
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Kazzess/libraries/sfqb"
+	"github.com/Kazzess/libraries/sfqb/sfqb_rqp"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+type queryAddress struct {
+	City   string  `db:"city"`
+	Street *string `db:"street"`
+}
+
+type QueryTimestamps struct {
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type queryUser struct {
+	ID      int64        `db:"id"`
+	Name    *string      `db:"name"`
+	Address queryAddress `db:"address"`
+	*QueryTimestamps
+}
+
+func TestScanTargets(t *testing.T) {
+	var user queryUser
+
+	v := reflect.ValueOf(&user).Elem()
+
+	targets, err := scanTargets(v, []pgconn.FieldDescription{
+		{Name: "id"},
+		{Name: "address_city"},
+		{Name: "created_at"},
+	})
+	require.NoError(t, err)
+	require.Len(t, targets, 3)
+	require.Same(t, &user.ID, targets[0])
+	require.Same(t, &user.Address.City, targets[1])
+	require.NotNil(t, user.QueryTimestamps)
+	require.Same(t, &user.CreatedAt, targets[2])
+
+	_, err = scanTargets(v, []pgconn.FieldDescription{{Name: "unknown"}})
+	require.ErrorIs(t, err, ErrUnknownColumn)
+}
+
+func TestRebindPlaceholders(t *testing.T) {
+	require.Equal(t, "a = $3 AND b IN ($4, $5) AND c = '?'", rebindPlaceholders("a = ? AND b IN (?, ?) AND c = '?'", 2))
+}
+
+func TestPageQueries(t *testing.T) {
+	query := sfqb_rqp.New()
+	query.AddFilter(sfqb.FilterField{Name: "status", Method: sfqb.EQ, Value: "active"})
+	query.SetSearch(sfqb.NewSearch("john", "name"))
+	query.AddSortBy("id", true)
+	query.SetLimit(10)
+	query.SetOffset(20)
+
+	pageSQL, countSQL, args := pageQueries("SELECT id, name, status FROM users WHERE org_id = $1", query, []any{7})
+
+	require.Equal(t,
+		"SELECT * FROM (SELECT id, name, status FROM users WHERE org_id = $1) AS q "+
+			`WHERE (status = $2) AND ("name"::text ILIKE $3 ESCAPE '\') ORDER BY id DESC LIMIT 10 OFFSET 20`,
+		pageSQL,
+	)
+	require.Equal(t,
+		"SELECT count(*) FROM (SELECT id, name, status FROM users WHERE org_id = $1) AS q "+
+			`WHERE (status = $2) AND ("name"::text ILIKE $3 ESCAPE '\')`,
+		countSQL,
+	)
+	require.Equal(t, []any{7, "active", "%john%"}, args)
+
+	query.SetSearch(sfqb.NewSearch(`50%_off\`, "title"))
+
+	_, _, args = pageQueries("SELECT id, title FROM promos", query, nil)
+	require.Equal(t, []any{"active", `%50\%\_off\\%`}, args)
+}
+
+func TestNotFoundError(t *testing.T) {
+	err := error(&NotFoundError{Query: "GetUser"})
+
+	require.True(t, IsNotFound(err))
+	require.True(t, errors.Is(err, pgx.ErrNoRows))
+	require.Equal(t, ErrorKindNoRows, ClassifyError(err).Kind)
+}
@@ -0,0 +1,255 @@
+package psql
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Balancer uint8
+
+const (
+	// RoundRobin spreads reads evenly across healthy replicas.
+	RoundRobin Balancer = iota
+	// LeastConnections picks the healthy replica with the fewest acquired connections.
+	LeastConnections
+)
+
+const replicationLagQuery = `SELECT COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) * 1000, 0)`
+
+type replicasConfig struct {
+	dsns        []string
+	pgxConfigs  []*pgxpool.Config
+	balancer    Balancer
+	maxLag      time.Duration
+	autoRouting bool
+}
+
+// WithReplicas adds read replicas. Read-only transactions and queries with the context
+// returned by WithReadReplica are routed to healthy replicas, the rest go to the primary.
+func WithReplicas(dsns ...string) Option {
+	return func(cfg *Config) {
+		cfg.replicas.dsns = append(cfg.replicas.dsns, dsns...)
+	}
+}
+
+// WithReplicaBalancer sets how a replica is chosen, RoundRobin by default.
+func WithReplicaBalancer(balancer Balancer) Option {
+	return func(cfg *Config) {
+		cfg.replicas.balancer = balancer
+	}
+}
+
+// WithMaxReplicationLag marks replicas lagging behind the primary for longer than maxLag
+// as unhealthy. Note that the replay lag of an idle database grows until the next write.
+// Zero value disables the lag check.
+func WithMaxReplicationLag(maxLag time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.replicas.maxLag = maxLag
+	}
+}
+
+// WithReplicaAutoRouting routes single SELECT queries outside transactions to replicas
+// without WithReadReplica. Use WithPrimary for reads that must see the latest writes.
+func WithReplicaAutoRouting(enabled bool) Option {
+	return func(cfg *Config) {
+		cfg.replicas.autoRouting = enabled
+	}
+}
+
+// parseReplicas parses replica DSNs and applies the primary connection settings set by options.
+func (cfg *Config) parseReplicas() error {
+	for _, dsn := range cfg.replicas.dsns {
+		pgxConfig, err := pgxpool.ParseConfig(dsn)
+		if err != nil {
+			return err
+		}
+
+		pgxConfig.ConnConfig.Tracer = cfg.pgxConfig.ConnConfig.Tracer
+		pgxConfig.ConnConfig.DefaultQueryExecMode = cfg.pgxConfig.ConnConfig.DefaultQueryExecMode
+		pgxConfig.AfterConnect = cfg.pgxConfig.AfterConnect
+		pgxConfig.BeforeAcquire = cfg.pgxConfig.BeforeAcquire
+		pgxConfig.AfterRelease = cfg.pgxConfig.AfterRelease
+
+		cfg.replicas.pgxConfigs = append(cfg.replicas.pgxConfigs, pgxConfig)
+	}
+
+	return nil
+}
+
+type routeKey struct{}
+
+type route uint8
+
+const (
+	routePrimary route = iota + 1
+	routeReplica
+)
+
+// WithReadReplica routes queries executed with the context to a replica.
+func WithReadReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, routeReplica)
+}
+
+// WithPrimary routes queries executed with the context to the primary even if
+// auto routing is enabled.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, routePrimary)
+}
+
+type replica struct {
+	pool    *pgxpool.Pool
+	labels  availability
+	healthy atomic.Bool
+	stats   poolStats
+}
+
+// newReplicas creates replica pools. Replicas are unhealthy until the first check passes,
+// so reads go to the primary while replicas are connecting.
+func newReplicas(ctx context.Context, cfg *Config) ([]*replica, error) {
+	replicas := make([]*replica, 0, len(cfg.replicas.pgxConfigs))
+
+	for i, pgxConfig := range cfg.replicas.pgxConfigs {
+		labels, err := availabilityLabels(cfg.replicas.dsns[i], roleReplica)
+		if err != nil {
+			closeReplicas(replicas)
+			return nil, err
+		}
+
+		pool, err := pgxpool.NewWithConfig(ctx, pgxConfig)
+		if err != nil {
+			closeReplicas(replicas)
+			return nil, err
+		}
+
+		r := &replica{pool: pool, labels: labels}
+		setNodeAvailability(labels, false)
+
+		replicas = append(replicas, r)
+	}
+
+	return replicas, nil
+}
+
+func closeReplicas(replicas []*replica) {
+	for _, r := range replicas {
+		r.pool.Close()
+	}
+}
+
+func checkReplicaAvailability(ctx context.Context, r *replica, cfg *Config) {
+	go func() {
+		ticker := time.NewTicker(cfg.health.intervalCheck)
+		defer ticker.Stop()
+
+		for {
+			r.check(ctx, cfg.replicas.maxLag)
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (r *replica) check(ctx context.Context, maxLag time.Duration) {
+	healthy := true
+
+	if err := r.pool.Ping(ctx); err != nil {
+		log.Printf("Failed to ping PostgreSQL replica %s due to error: %v\n", r.labels.host, err)
+
+		healthy = false
+	}
+
+	if healthy && maxLag > 0 {
+		var lagMs float64
+
+		err := r.pool.QueryRow(WithQueryName(ctx, "replication_lag"), replicationLagQuery).Scan(&lagMs)
+		if err != nil {
+			log.Printf("Failed to check PostgreSQL replica %s lag due to error: %v\n", r.labels.host, err)
+
+			healthy = false
+		} else {
+			replicationLagMs.WithLabelValues(r.labels.host, r.labels.database).Set(lagMs)
+			healthy = time.Duration(lagMs*float64(time.Millisecond)) <= maxLag
+		}
+	}
+
+	r.healthy.Store(healthy)
+	setNodeAvailability(r.labels, healthy)
+	r.stats.observe(r.pool, r.labels)
+}
+
+// Replica returns a healthy replica pool or the primary pool if there are none.
+func (c *Client) Replica() *pgxpool.Pool {
+	healthy := make([]*replica, 0, len(c.replicas))
+
+	for _, r := range c.replicas {
+		if r.healthy.Load() {
+			healthy = append(healthy, r)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return c.Pool
+	}
+
+	if c.balancer == LeastConnections {
+		chosen := healthy[0]
+
+		for _, r := range healthy[1:] {
+			if r.pool.Stat().AcquiredConns() < chosen.pool.Stat().AcquiredConns() {
+				chosen = r
+			}
+		}
+
+		return chosen.pool
+	}
+
+	return healthy[c.next.Add(1)%uint64(len(healthy))].pool
+}
+
+// pool returns the pool for a query outside transactions.
+func (c *Client) pool(ctx context.Context, sql string) *pgxpool.Pool {
+	if len(c.replicas) == 0 {
+		return c.Pool
+	}
+
+	switch ctx.Value(routeKey{}) {
+	case routeReplica:
+		return c.Replica()
+	case routePrimary:
+		return c.Pool
+	}
+
+	if c.autoRouting && isReadOnlyQuery(sql) {
+		return c.Replica()
+	}
+
+	return c.Pool
+}
+
+func isReadOnlyQuery(sql string) bool {
+	if queryOperation(sql) != "SELECT" {
+		return false
+	}
+
+	upper := strings.Join(strings.Fields(strings.ToUpper(sql)), " ")
+
+	return !strings.Contains(upper, " FOR UPDATE") &&
+		!strings.Contains(upper, " FOR SHARE") &&
+		!strings.Contains(upper, " FOR NO KEY UPDATE") &&
+		!strings.Contains(upper, " FOR KEY SHARE")
+}
+
+// Close closes the primary and replica pools.
+func (c *Client) Close() {
+	closeReplicas(c.replicas)
+	c.Pool.Close()
+}
@@ -0,0 +1,65 @@
+package psql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPool(t *testing.T, host string) *pgxpool.Pool {
+	t.Helper()
+
+	pool, err := pgxpool.New(context.Background(), "postgres://user:pass@"+host+":5432/db")
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+func TestClient_Replica(t *testing.T) {
+	client := &Client{Pool: newTestPool(t, "primary")}
+
+	first := &replica{pool: newTestPool(t, "replica-1")}
+	second := &replica{pool: newTestPool(t, "replica-2")}
+	client.replicas = []*replica{first, second}
+
+	require.Same(t, client.Pool, client.Replica())
+
+	first.healthy.Store(true)
+	second.healthy.Store(true)
+
+	picked := map[*pgxpool.Pool]int{}
+	for range 4 {
+		picked[client.Replica()]++
+	}
+
+	require.Equal(t, 2, picked[first.pool])
+	require.Equal(t, 2, picked[second.pool])
+
+	second.healthy.Store(false)
+	require.Same(t, first.pool, client.Replica())
+
+	client.balancer = LeastConnections
+	require.Same(t, first.pool, client.Replica())
+}
+
+func TestClient_Routing(t *testing.T) {
+	client := &Client{Pool: newTestPool(t, "primary")}
+
+	r := &replica{pool: newTestPool(t, "replica")}
+	r.healthy.Store(true)
+	client.replicas = []*replica{r}
+
+	ctx := context.Background()
+
+	require.Same(t, client.Pool, client.pool(ctx, "SELECT 1"))
+	require.Same(t, r.pool, client.pool(WithReadReplica(ctx), "SELECT 1"))
+
+	client.autoRouting = true
+	require.Same(t, r.pool, client.pool(ctx, "SELECT 1"))
+	require.Same(t, client.Pool, client.pool(ctx, "SELECT * FROM users\nFOR UPDATE"))
+	require.Same(t, client.Pool, client.pool(ctx, "UPDATE users SET name = $1"))
+	require.Same(t, client.Pool, client.pool(WithPrimary(ctx), "SELECT 1"))
+}
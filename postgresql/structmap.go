@@ -0,0 +1,160 @@
+package psql
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const structTag = "db"
+
+var (
+	ErrNotStruct       = errors.New("rows must be structs or pointers to structs")
+	ErrNilRow          = errors.New("rows must not be nil")
+	ErrUnknownColumn   = errors.New("column has no struct field")
+	ErrUnsettableField = errors.New("cannot allocate embedded pointer to unexported struct")
+)
+
+// structField is a column mapped to a (possibly embedded) struct field.
+type structField struct {
+	column string
+	index  []int
+}
+
+var structFieldsCache sync.Map
+
+// structFields returns the columns of struct type t. The column name is taken from the
+// "db" tag or the snake_case field name, fields tagged with "-" and unexported fields
+// are skipped, fields of embedded structs are promoted. Fields of nested structs with
+// "db" tags are prefixed by the parent column, e.g. "address_city".
+func structFields(t reflect.Type) []structField {
+	if cached, ok := structFieldsCache.Load(t); ok {
+		return cached.([]structField)
+	}
+
+	fields := collectStructFields(t, nil, "")
+	structFieldsCache.Store(t, fields)
+
+	return fields
+}
+
+func collectStructFields(t reflect.Type, parent []int, prefix string) []structField {
+	var fields []structField
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		tag, hasTag := field.Tag.Lookup(structTag)
+		if tag == "-" {
+			continue
+		}
+
+		index := append(append([]int(nil), parent...), i)
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && !hasTag && fieldType.Kind() == reflect.Struct {
+			fields = append(fields, collectStructFields(fieldType, index, prefix)...)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		column := tag
+		if column == "" {
+			column = toSnakeCase(field.Name)
+		}
+
+		if isNestedStruct(fieldType) {
+			fields = append(fields, collectStructFields(fieldType, index, prefix+column+"_")...)
+			continue
+		}
+
+		fields = append(fields, structField{column: prefix + column, index: index})
+	}
+
+	return fields
+}
+
+// isNestedStruct reports whether t is a struct with "db" tags. Other structs, e.g.
+// time.Time or pgtype.Text, are scanned as a single column.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := range t.NumField() {
+		if _, ok := t.Field(i).Tag.Lookup(structTag); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fieldValue returns the field value or nil if an embedded pointer on the path is nil.
+func fieldValue(v reflect.Value, index []int) any {
+	field, err := v.FieldByIndexErr(index)
+	if err != nil {
+		return nil
+	}
+
+	return field.Interface()
+}
+
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if i > 0 && (unicode.IsLower(runes[i-1]) || nextIsLower) {
+				b.WriteByte('_')
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// tableIdentifier splits a possibly schema qualified table name.
+func tableIdentifier(table string) pgx.Identifier {
+	return strings.Split(table, ".")
+}
+
+// fieldAddr returns the pointer to the field allocating nil pointers on the path.
+func fieldAddr(v reflect.Value, index []int) (any, error) {
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return nil, fmt.Errorf("%w: %s", ErrUnsettableField, v.Type())
+				}
+
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(idx)
+	}
+
+	return v.Addr().Interface(), nil
+}
@@ -0,0 +1,163 @@
+package psql
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultTenantVariable     = "app.tenant_id"
+	defaultTenantResetTimeout = 5 * time.Second
+	tenantCustomDataKey       = "psql.tenant"
+	searchPathSetting         = "search_path"
+)
+
+type TenantMode uint8
+
+const (
+	// TenantSchema sets search_path to the tenant schema followed by the shared schemas.
+	TenantSchema TenantMode = iota
+	// TenantVariable sets the session variable used by row-level security policies,
+	// e.g. USING (tenant_id = current_setting('app.tenant_id')::uuid).
+	TenantVariable
+)
+
+type tenantKey struct{}
+
+// ContextWithTenant makes connections acquired with the context scoped to the tenant.
+func ContextWithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+type TenantConfig struct {
+	mode          TenantMode
+	variable      string
+	sharedSchemas []string
+	schemaName    func(tenantID string) string
+}
+
+type TenantOption func(*TenantConfig)
+
+// WithTenantVariable sets the session variable for TenantVariable, "app.tenant_id" by default.
+func WithTenantVariable(name string) TenantOption {
+	return func(cfg *TenantConfig) {
+		cfg.variable = name
+	}
+}
+
+// WithSharedSchemas sets the schemas following the tenant schema in search_path, "public" by default.
+func WithSharedSchemas(schemas ...string) TenantOption {
+	return func(cfg *TenantConfig) {
+		cfg.sharedSchemas = schemas
+	}
+}
+
+// WithTenantSchemaName sets the schema name for the tenant, the tenant ID by default.
+func WithTenantSchemaName(fn func(tenantID string) string) TenantOption {
+	return func(cfg *TenantConfig) {
+		cfg.schemaName = fn
+	}
+}
+
+// WithTenants scopes connections acquired with ContextWithTenant to the tenant. The setting
+// is applied in BeforeAcquire, so it covers queries, batches and transactions started
+// with the context, and reset in AfterRelease. Connections which fail to reset are
+// closed instead of returning to the pool. Hooks already set in the pool config are kept.
+func WithTenants(mode TenantMode, options ...TenantOption) Option {
+	return func(cfg *Config) {
+		config := &TenantConfig{
+			mode:          mode,
+			variable:      defaultTenantVariable,
+			sharedSchemas: []string{"public"},
+			schemaName: func(tenantID string) string {
+				return tenantID
+			},
+		}
+
+		for _, o := range options {
+			o(config)
+		}
+
+		beforeAcquire := cfg.pgxConfig.BeforeAcquire
+		afterRelease := cfg.pgxConfig.AfterRelease
+
+		cfg.pgxConfig.BeforeAcquire = func(ctx context.Context, conn *pgx.Conn) bool {
+			if beforeAcquire != nil && !beforeAcquire(ctx, conn) {
+				return false
+			}
+
+			return config.apply(ctx, conn)
+		}
+
+		cfg.pgxConfig.AfterRelease = func(conn *pgx.Conn) bool {
+			if !config.reset(conn) {
+				return false
+			}
+
+			return afterRelease == nil || afterRelease(conn)
+		}
+	}
+}
+
+// setting returns the run-time parameter and its value for the tenant.
+func (cfg *TenantConfig) setting(tenantID string) (name, value string) {
+	if cfg.mode == TenantVariable {
+		return cfg.variable, tenantID
+	}
+
+	schemas := make([]string, 0, len(cfg.sharedSchemas)+1)
+	schemas = append(schemas, pgx.Identifier{cfg.schemaName(tenantID)}.Sanitize())
+
+	for _, schema := range cfg.sharedSchemas {
+		schemas = append(schemas, pgx.Identifier{schema}.Sanitize())
+	}
+
+	return searchPathSetting, strings.Join(schemas, ", ")
+}
+
+func (cfg *TenantConfig) apply(ctx context.Context, conn *pgx.Conn) bool {
+	tenantID, ok := TenantFromContext(ctx)
+	if !ok {
+		return true
+	}
+
+	name, value := cfg.setting(tenantID)
+
+	// Mark before setting, so a partially applied setting is reset as well.
+	conn.PgConn().CustomData()[tenantCustomDataKey] = name
+
+	if _, err := conn.Exec(ctx, "SELECT set_config($1, $2, false)", name, value); err != nil {
+		log.Printf("Failed to set PostgreSQL tenant %s due to error: %v\n", tenantID, err)
+		return false
+	}
+
+	return true
+}
+
+func (cfg *TenantConfig) reset(conn *pgx.Conn) bool {
+	name, ok := conn.PgConn().CustomData()[tenantCustomDataKey].(string)
+	if !ok {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTenantResetTimeout)
+	defer cancel()
+
+	if _, err := conn.Exec(ctx, "RESET "+pgx.Identifier(strings.Split(name, ".")).Sanitize()); err != nil {
+		log.Printf("Failed to reset PostgreSQL tenant setting due to error: %v\n", err)
+		return false
+	}
+
+	delete(conn.PgConn().CustomData(), tenantCustomDataKey)
+
+	return true
+}
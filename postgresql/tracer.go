@@ -0,0 +1,232 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Kazzess/libraries/logging"
+	"github.com/Kazzess/libraries/tracing"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultSlowQueryThreshold = time.Second
+	maxTracedArgLength        = 64
+	unnamedQuery              = "unnamed"
+)
+
+type QueryTracerConfig struct {
+	spans              bool
+	args               bool
+	slowQueryThreshold time.Duration
+}
+
+type QueryTracerOption func(*QueryTracerConfig)
+
+// WithSpans enables OpenTelemetry spans for queries.
+func WithSpans(spans bool) QueryTracerOption {
+	return func(cfg *QueryTracerConfig) {
+		cfg.spans = spans
+	}
+}
+
+// WithQueryArgs enables recording of query arguments in spans and slow-query logs, disabled
+// by default. Arguments are only truncated, so enable it only if they hold no secrets or PII.
+func WithQueryArgs(args bool) QueryTracerOption {
+	return func(cfg *QueryTracerConfig) {
+		cfg.args = args
+	}
+}
+
+// WithSlowQueryThreshold sets the duration after which queries are logged as slow.
+// Non-positive threshold disables slow-query logging.
+func WithSlowQueryThreshold(threshold time.Duration) QueryTracerOption {
+	return func(cfg *QueryTracerConfig) {
+		cfg.slowQueryThreshold = threshold
+	}
+}
+
+// WithQueryTracer installs QueryTracer into the pool config. By default it records spans
+// without arguments and logs queries slower than a second.
+func WithQueryTracer(options ...QueryTracerOption) Option {
+	return func(cfg *Config) {
+		cfg.pgxConfig.ConnConfig.Tracer = NewQueryTracer(options...)
+	}
+}
+
+type queryNameKey struct{}
+
+// WithQueryName names queries executed with the context for metrics and spans.
+// Queries starting with the sqlc-style comment "-- name: GetUser :one" are named automatically.
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameKey{}, name)
+}
+
+type queryTraceKey struct{}
+
+type queryTrace struct {
+	start     time.Time
+	name      string
+	operation string
+	sql       string
+	args      []any
+	span      trace.Span
+}
+
+// QueryTracer implements pgx.QueryTracer.
+type QueryTracer struct {
+	config *QueryTracerConfig
+}
+
+var _ pgx.QueryTracer = (*QueryTracer)(nil)
+
+func NewQueryTracer(options ...QueryTracerOption) *QueryTracer {
+	config := &QueryTracerConfig{
+		spans:              true,
+		slowQueryThreshold: defaultSlowQueryThreshold,
+	}
+
+	for _, o := range options {
+		o(config)
+	}
+
+	return &QueryTracer{config: config}
+}
+
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	qt := &queryTrace{
+		start:     time.Now(),
+		name:      queryName(ctx, data.SQL),
+		operation: queryOperation(data.SQL),
+		sql:       data.SQL,
+		args:      data.Args,
+	}
+
+	if t.config.spans {
+		attrs := []attribute.KeyValue{
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", qt.operation),
+			attribute.String("db.statement", PrettySQL(data.SQL)),
+		}
+
+		if conn != nil {
+			attrs = append(attrs,
+				attribute.String("db.name", conn.Config().Database),
+				attribute.String("server.address", conn.Config().Host),
+			)
+		}
+
+		if t.config.args {
+			attrs = append(attrs, attribute.StringSlice("db.args", SanitizeArgs(data.Args)))
+		}
+
+		ctx, qt.span = tracing.Start(
+			ctx,
+			"postgres "+qt.name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attrs...),
+		)
+	}
+
+	return context.WithValue(ctx, queryTraceKey{}, qt)
+}
+
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	qt, ok := ctx.Value(queryTraceKey{}).(*queryTrace)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(qt.start)
+
+	observeQuery(qt.name, qt.operation, data.Err, duration)
+
+	if qt.span != nil {
+		qt.span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+		tracing.Error(ctx, data.Err)
+		qt.span.End()
+	}
+
+	if t.config.slowQueryThreshold > 0 && duration >= t.config.slowQueryThreshold {
+		attrs := []logging.Attr{
+			logging.StringAttr("query", qt.name),
+			logging.StringAttr("sql", PrettySQL(qt.sql)),
+			logging.DurationAttr("duration", duration),
+			logging.Int64Attr("rows_affected", data.CommandTag.RowsAffected()),
+		}
+
+		if t.config.args {
+			attrs = append(attrs, logging.AnyAttr("args", SanitizeArgs(qt.args)))
+		}
+
+		if data.Err != nil {
+			attrs = append(attrs, logging.ErrAttr(data.Err))
+		}
+
+		logging.WithAttrs(ctx, attrs...).Warn("slow query")
+	}
+}
+
+// SanitizeArgs formats query arguments for logs and spans: binary values are replaced
+// by their size and long values are truncated.
+func SanitizeArgs(args []any) []string {
+	sanitized := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		var value string
+
+		switch v := arg.(type) {
+		case nil:
+			value = "NULL"
+		case []byte:
+			value = fmt.Sprintf("<%d bytes>", len(v))
+		case string:
+			value = v
+		default:
+			value = fmt.Sprintf("%v", v)
+		}
+
+		if utf8.RuneCountInString(value) > maxTracedArgLength {
+			value = string([]rune(value)[:maxTracedArgLength]) + "..."
+		}
+
+		sanitized = append(sanitized, value)
+	}
+
+	return sanitized
+}
+
+func queryName(ctx context.Context, sql string) string {
+	if name, ok := ctx.Value(queryNameKey{}).(string); ok && name != "" {
+		return name
+	}
+
+	sql = strings.TrimSpace(sql)
+	if rest, ok := strings.CutPrefix(sql, "-- name:"); ok {
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+
+	return unnamedQuery
+}
+
+func queryOperation(sql string) string {
+	for _, line := range strings.Split(sql, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+
+		if fields := strings.Fields(line); len(fields) > 0 {
+			return strings.ToUpper(fields[0])
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,35 @@
+package psql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "GetUser", queryName(context.Background(), "-- name: GetUser :one\nSELECT * FROM users WHERE id = $1"))
+	require.Equal(t, unnamedQuery, queryName(context.Background(), "SELECT 1"))
+	require.Equal(t, "ping", queryName(WithQueryName(context.Background(), "ping"), "-- name: GetUser :one\nSELECT 1"))
+}
+
+func TestQueryOperation(t *testing.T) {
+	require.Equal(t, "SELECT", queryOperation("-- name: GetUser :one\n  select * from users"))
+	require.Equal(t, "INSERT", queryOperation("INSERT INTO users (id) VALUES ($1)"))
+	require.Equal(t, "", queryOperation("-- only comment"))
+}
+
+func TestNewQueryTracer(t *testing.T) {
+	require.False(t, NewQueryTracer().config.args, "arguments may hold secrets")
+	require.True(t, NewQueryTracer(WithQueryArgs(true)).config.args)
+}
+
+func TestSanitizeArgs(t *testing.T) {
+	args := SanitizeArgs([]any{nil, []byte("secret"), 42, strings.Repeat("a", 100)})
+
+	require.Equal(t, "NULL", args[0])
+	require.Equal(t, "<6 bytes>", args[1])
+	require.Equal(t, "42", args[2])
+	require.Equal(t, strings.Repeat("a", maxTracedArgLength)+"...", args[3])
+}
@@ -0,0 +1,212 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Kazzess/libraries/core/repeat"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	defaultTxRetryMinDelay = 10 * time.Millisecond
+	defaultTxRetryMaxDelay = 500 * time.Millisecond
+)
+
+// Querier is implemented by both the pool and transactions.
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+var (
+	_ Querier = (*Client)(nil)
+	_ Querier = (pgx.Tx)(nil)
+)
+
+// TxOptions configures WithTx. Zero value starts a read-write transaction
+// with the default isolation level and without retries.
+type TxOptions struct {
+	IsoLevel pgx.TxIsoLevel
+	ReadOnly bool
+	// MaxRetries is the number of retries on serialization failures and deadlocks.
+	MaxRetries int
+}
+
+func (o TxOptions) pgxOptions() pgx.TxOptions {
+	options := pgx.TxOptions{
+		IsoLevel:   o.IsoLevel,
+		AccessMode: pgx.ReadWrite,
+	}
+
+	if o.ReadOnly {
+		options.AccessMode = pgx.ReadOnly
+	}
+
+	return options
+}
+
+type txKey struct{}
+
+// TxFromContext returns the transaction started by WithTx.
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// ContextWithTx adds the transaction to the context, so Client methods use it.
+func ContextWithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// WithTx runs fn in a transaction stored in the context passed to fn, so Client.Exec,
+// Client.Query and other Querier methods called with that context run inside the transaction.
+// The transaction is committed if fn returns nil and rolled back otherwise or on panic.
+// Read-only transactions run on a replica unless they are serializable.
+// Nested calls create savepoints and ignore opts. Serialization failures and deadlocks
+// of the outermost transaction are retried up to opts.MaxRetries times.
+func (c *Client) WithTx(ctx context.Context, opts TxOptions, fn func(ctx context.Context) error) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		return runTx(ctx, tx.Begin, fn)
+	}
+
+	pool := c.Pool
+	if opts.ReadOnly && opts.IsoLevel != pgx.Serializable {
+		// Hot standby does not support serializable transactions.
+		pool = c.Replica()
+	}
+
+	begin := func(ctx context.Context) (pgx.Tx, error) {
+		return pool.BeginTx(ctx, opts.pgxOptions())
+	}
+
+	return retryTx(ctx, opts.MaxRetries, begin, fn)
+}
+
+// retryTx runs the transaction and retries it on serialization failures and deadlocks.
+func retryTx(
+	ctx context.Context,
+	maxRetries int,
+	begin func(ctx context.Context) (pgx.Tx, error),
+	fn func(ctx context.Context) error,
+) error {
+	if maxRetries <= 0 {
+		return runTx(ctx, begin, fn)
+	}
+
+	return repeat.Exec(
+		ctx,
+		func(ctx context.Context) error {
+			return runTx(ctx, begin, fn)
+		},
+		repeat.WithMaxRetries(maxRetries+1),
+		repeat.WithMinTimeWait(defaultTxRetryMinDelay),
+		repeat.WithMaxTimeWait(defaultTxRetryMaxDelay),
+		repeat.WithBackoffFactor(retryBackoffFactor),
+		repeat.WithErrorHandler(IsRetryableTxError),
+	)
+}
+
+func runTx(
+	ctx context.Context,
+	begin func(ctx context.Context) (pgx.Tx, error),
+	fn func(ctx context.Context) error,
+) (err error) {
+	tx, err := begin(ctx)
+	if err != nil {
+		return ErrCreateTx(err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback(ctx)
+			panic(p)
+		}
+	}()
+
+	if err = fn(ContextWithTx(ctx, tx)); err != nil {
+		if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+			return errors.Join(err, ErrRollback(rollbackErr))
+		}
+
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return ErrCommit(err)
+	}
+
+	return nil
+}
+
+// IsRetryableTxError reports whether the transaction failed due to a serialization
+// failure or a deadlock and can be retried from the beginning.
+func IsRetryableTxError(err error) bool {
+	return IsErrorKind(err, ErrorKindSerializationFailure) || IsErrorKind(err, ErrorKindDeadlock)
+}
+
+// Querier returns the transaction from the context, a replica if the context is
+// marked by WithReadReplica or the primary pool.
+func (c *Client) Querier(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+
+	return c.pool(ctx, "")
+}
+
+// primary returns the transaction from the context or the primary pool for writes.
+func (c *Client) primary(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+
+	return c.Pool
+}
+
+func (c *Client) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return c.primary(ctx).Exec(ctx, sql, arguments...)
+}
+
+func (c *Client) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Query(ctx, sql, args...)
+	}
+
+	return c.pool(ctx, sql).Query(ctx, sql, args...)
+}
+
+func (c *Client) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.QueryRow(ctx, sql, args...)
+	}
+
+	return c.pool(ctx, sql).QueryRow(ctx, sql, args...)
+}
+
+func (c *Client) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return c.primary(ctx).SendBatch(ctx, b)
+}
+
+func (c *Client) CopyFrom(
+	ctx context.Context,
+	tableName pgx.Identifier,
+	columnNames []string,
+	rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	return c.primary(ctx).CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+// Begin starts a transaction or a savepoint if the context already has one.
+func (c *Client) Begin(ctx context.Context) (pgx.Tx, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Begin(ctx)
+	}
+
+	return c.Pool.Begin(ctx)
+}
@@ -0,0 +1,154 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// testTx records the calls of the transaction and its savepoints.
+type testTx struct {
+	pgx.Tx
+	name      string
+	events    *[]string
+	commitErr error
+}
+
+func newTestTx(name string) *testTx {
+	return &testTx{name: name, events: &[]string{}}
+}
+
+func (tx *testTx) Begin(context.Context) (pgx.Tx, error) {
+	*tx.events = append(*tx.events, tx.name+".savepoint")
+
+	return &testTx{name: tx.name + ".savepoint", events: tx.events}, nil
+}
+
+func (tx *testTx) Commit(context.Context) error {
+	*tx.events = append(*tx.events, tx.name+".commit")
+
+	return tx.commitErr
+}
+
+func (tx *testTx) Rollback(context.Context) error {
+	*tx.events = append(*tx.events, tx.name+".rollback")
+
+	return nil
+}
+
+func (tx *testTx) begin(context.Context) (pgx.Tx, error) {
+	*tx.events = append(*tx.events, tx.name+".begin")
+
+	return tx, nil
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	require.True(t, IsRetryableTxError(ErrCommit(&PgError{Code: pgerrcode.SerializationFailure})))
+	require.True(t, IsRetryableTxError(&PgError{Code: pgerrcode.DeadlockDetected}))
+	require.False(t, IsRetryableTxError(&PgError{Code: pgerrcode.UniqueViolation}))
+	require.False(t, IsRetryableTxError(errors.New("connection reset")))
+}
+
+func TestRunTx_Commit(t *testing.T) {
+	tx := newTestTx("tx")
+
+	err := runTx(context.Background(), tx.begin, func(ctx context.Context) error {
+		ctxTx, ok := TxFromContext(ctx)
+		require.True(t, ok)
+		require.Same(t, tx, ctxTx)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"tx.begin", "tx.commit"}, *tx.events)
+}
+
+func TestRunTx_Rollback(t *testing.T) {
+	tx := newTestTx("tx")
+	fnErr := errors.New("insufficient funds")
+
+	err := runTx(context.Background(), tx.begin, func(ctx context.Context) error {
+		return fnErr
+	})
+	require.ErrorIs(t, err, fnErr)
+	require.Equal(t, []string{"tx.begin", "tx.rollback"}, *tx.events)
+}
+
+func TestRunTx_PanicRollback(t *testing.T) {
+	tx := newTestTx("tx")
+
+	require.PanicsWithValue(t, "boom", func() {
+		_ = runTx(context.Background(), tx.begin, func(ctx context.Context) error {
+			panic("boom")
+		})
+	})
+	require.Equal(t, []string{"tx.begin", "tx.rollback"}, *tx.events)
+}
+
+func TestClient_WithTxSavepoint(t *testing.T) {
+	tx := newTestTx("tx")
+	client := &Client{}
+
+	err := runTx(context.Background(), tx.begin, func(ctx context.Context) error {
+		// The nested call rolls back to the savepoint, the outer transaction commits.
+		nestedErr := client.WithTx(ctx, TxOptions{}, func(ctx context.Context) error {
+			savepoint, ok := TxFromContext(ctx)
+			require.True(t, ok)
+			require.NotSame(t, tx, savepoint)
+
+			return errors.New("skip item")
+		})
+		require.Error(t, nestedErr)
+
+		return client.WithTx(ctx, TxOptions{}, func(ctx context.Context) error { return nil })
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"tx.begin",
+		"tx.savepoint", "tx.savepoint.rollback",
+		"tx.savepoint", "tx.savepoint.commit",
+		"tx.commit",
+	}, *tx.events)
+}
+
+func TestRetryTx(t *testing.T) {
+	tx := newTestTx("tx")
+	tx.commitErr = &PgError{Code: pgerrcode.SerializationFailure}
+
+	var calls int
+
+	err := retryTx(context.Background(), 2, tx.begin, func(ctx context.Context) error {
+		calls++
+
+		if calls == 2 {
+			tx.commitErr = nil
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+
+	calls = 0
+	tx.commitErr = &PgError{Code: pgerrcode.SerializationFailure}
+
+	err = retryTx(context.Background(), 2, tx.begin, func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	require.True(t, IsRetryableTxError(err))
+	require.Equal(t, 3, calls, "the first attempt and 2 retries")
+
+	calls = 0
+
+	err = retryTx(context.Background(), 2, tx.begin, func(ctx context.Context) error {
+		calls++
+		return &PgError{Code: pgerrcode.UniqueViolation}
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, calls, "other errors are not retried")
+}
@@ -0,0 +1,350 @@
+package redis
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultCacheName        = "default"
+	defaultEarlyRefreshBeta = 1.0
+
+	cacheEntryValue    byte = 1
+	cacheEntryNegative byte = 2
+	cacheHeaderSize         = 1 + 8 + 8
+)
+
+var (
+	// ErrCacheMiss is returned by Cache.Get when the key is absent.
+	ErrCacheMiss = errors.New("cache miss")
+	// ErrNotFound must be returned (or wrapped) by a loader to report that the value
+	// does not exist. With negative caching enabled the absence is cached too.
+	ErrNotFound = errors.New("value not found")
+	// ErrBrokenCacheEntry is returned when a cached value cannot be decoded.
+	ErrBrokenCacheEntry = errors.New("broken cache entry")
+)
+
+// Loader loads the value when it is missing in the cache.
+type Loader[T any] func(ctx context.Context) (T, error)
+
+type CacheConfig struct {
+	name             string
+	namespace        string
+	codec            Codec
+	negativeTTL      time.Duration
+	earlyRefresh     bool
+	earlyRefreshBeta float64
+}
+
+type CacheOption func(*CacheConfig)
+
+// WithCacheName sets the cache name used as the metrics label.
+func WithCacheName(name string) CacheOption {
+	return func(cfg *CacheConfig) {
+		cfg.name = name
+	}
+}
+
+// WithNamespace prefixes every key with "<namespace>:".
+func WithNamespace(namespace string) CacheOption {
+	return func(cfg *CacheConfig) {
+		cfg.namespace = namespace
+	}
+}
+
+// WithCodec sets the codec for values. JSONCodec is used by default.
+func WithCodec(codec Codec) CacheOption {
+	return func(cfg *CacheConfig) {
+		cfg.codec = codec
+	}
+}
+
+// WithNegativeTTL enables caching of ErrNotFound returned by a loader for the given ttl.
+func WithNegativeTTL(ttl time.Duration) CacheOption {
+	return func(cfg *CacheConfig) {
+		cfg.negativeTTL = ttl
+	}
+}
+
+// WithEarlyRefresh enables probabilistic early refresh (XFetch). Beta greater than 1
+// favors earlier refreshes, non-positive beta sets the default value 1.
+func WithEarlyRefresh(beta float64) CacheOption {
+	return func(cfg *CacheConfig) {
+		cfg.earlyRefresh = true
+		cfg.earlyRefreshBeta = beta
+	}
+}
+
+// Cache is a typed cache on top of Client. Concurrent loads of the same key
+// inside one process are deduplicated.
+type Cache[T any] struct {
+	client *Client
+	config *CacheConfig
+	group  singleflight.Group
+}
+
+func NewCache[T any](client *Client, options ...CacheOption) *Cache[T] {
+	config := &CacheConfig{
+		name:  defaultCacheName,
+		codec: JSONCodec,
+	}
+
+	for _, o := range options {
+		o(config)
+	}
+
+	if config.earlyRefresh && config.earlyRefreshBeta <= 0 {
+		config.earlyRefreshBeta = defaultEarlyRefreshBeta
+	}
+
+	return &Cache[T]{
+		client: client,
+		config: config,
+	}
+}
+
+type cacheEntry struct {
+	kind      byte
+	delta     time.Duration
+	expiresAt time.Time
+	payload   []byte
+}
+
+// Get returns the cached value, ErrCacheMiss if the key is absent
+// or ErrNotFound if the absence of the value is cached.
+func (c *Cache[T]) Get(ctx context.Context, key string) (T, error) {
+	var value T
+
+	start := time.Now()
+
+	entry, err := c.get(ctx, c.key(key))
+	if err != nil {
+		observeCache(c.config.name, cacheResultFromErr(err), start)
+		return value, err
+	}
+
+	if entry.kind == cacheEntryNegative {
+		observeCache(c.config.name, cacheResultNegativeHit, start)
+		return value, ErrNotFound
+	}
+
+	value, err = c.decode(entry.payload)
+	if err != nil {
+		observeCache(c.config.name, cacheResultError, start)
+		return value, err
+	}
+
+	observeCache(c.config.name, cacheResultHit, start)
+
+	return value, nil
+}
+
+// Set stores the value for ttl.
+func (c *Cache[T]) Set(ctx context.Context, key string, value T, ttl time.Duration) error {
+	payload, err := c.config.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return c.set(ctx, c.key(key), cacheEntryValue, payload, 0, ttl)
+}
+
+// Delete removes the keys from the cache.
+func (c *Cache[T]) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	fullKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fullKeys = append(fullKeys, c.key(key))
+	}
+
+	if err := c.client.Del(ctx, fullKeys...).Err(); err != nil {
+		return ErrDel(err)
+	}
+
+	return nil
+}
+
+// GetOrLoad returns the cached value or calls the loader and stores its result for ttl.
+// Concurrent calls for the same key share one loader call, which completes even if
+// the callers are cancelled.
+func (c *Cache[T]) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader Loader[T]) (T, error) {
+	var value T
+
+	start := time.Now()
+	fullKey := c.key(key)
+
+	entry, err := c.get(ctx, fullKey)
+	switch {
+	case err == nil && !c.shouldRefresh(entry):
+		if entry.kind == cacheEntryNegative {
+			observeCache(c.config.name, cacheResultNegativeHit, start)
+			return value, ErrNotFound
+		}
+
+		value, err = c.decode(entry.payload)
+		if err == nil {
+			observeCache(c.config.name, cacheResultHit, start)
+			return value, nil
+		}
+
+		observeCache(c.config.name, cacheResultError, start)
+	case err == nil:
+		observeCache(c.config.name, cacheResultEarlyRefresh, start)
+	case errors.Is(err, ErrCacheMiss):
+		observeCache(c.config.name, cacheResultMiss, start)
+	default:
+		// Redis is unavailable: fall through to the loader, the cache is best effort.
+		observeCache(c.config.name, cacheResultError, start)
+	}
+
+	// The load is shared by the waiters, so it isn't cancelled with the first caller,
+	// but each caller stops waiting when its own ctx is done.
+	loaded := c.group.DoChan(fullKey, func() (interface{}, error) {
+		return c.load(context.WithoutCancel(ctx), fullKey, ttl, loader)
+	})
+
+	select {
+	case <-ctx.Done():
+		return value, ctx.Err()
+	case result := <-loaded:
+		if result.Err != nil {
+			return value, result.Err
+		}
+
+		// The type assertion fails for nil interface values.
+		value, _ = result.Val.(T)
+
+		return value, nil
+	}
+}
+
+func (c *Cache[T]) load(ctx context.Context, fullKey string, ttl time.Duration, loader Loader[T]) (T, error) {
+	start := time.Now()
+
+	value, err := loader(ctx)
+	delta := time.Since(start)
+
+	observeCacheLoad(c.config.name, err, delta)
+
+	if err != nil {
+		if errors.Is(err, ErrNotFound) && c.config.negativeTTL > 0 {
+			_ = c.set(ctx, fullKey, cacheEntryNegative, nil, delta, c.config.negativeTTL)
+		}
+
+		return value, err
+	}
+
+	payload, err := c.config.codec.Marshal(value)
+	if err != nil {
+		return value, err
+	}
+
+	// The loaded value is returned even if it cannot be stored.
+	_ = c.set(ctx, fullKey, cacheEntryValue, payload, delta, ttl)
+
+	return value, nil
+}
+
+func (c *Cache[T]) get(ctx context.Context, fullKey string) (cacheEntry, error) {
+	data, err := c.client.Get(ctx, fullKey).Bytes()
+	if err != nil {
+		if errors.Is(err, Nil) {
+			return cacheEntry{}, ErrCacheMiss
+		}
+
+		return cacheEntry{}, ErrGet(err)
+	}
+
+	return decodeCacheEntry(data)
+}
+
+func (c *Cache[T]) set(
+	ctx context.Context,
+	fullKey string,
+	kind byte,
+	payload []byte,
+	delta, ttl time.Duration,
+) error {
+	data := encodeCacheEntry(cacheEntry{
+		kind:      kind,
+		delta:     delta,
+		expiresAt: time.Now().Add(ttl),
+		payload:   payload,
+	})
+
+	if err := c.client.Set(ctx, fullKey, data, ttl).Err(); err != nil {
+		return ErrSet(err)
+	}
+
+	return nil
+}
+
+func (c *Cache[T]) decode(payload []byte) (T, error) {
+	var value T
+
+	if err := c.config.codec.Unmarshal(payload, &value); err != nil {
+		return value, errors.Join(ErrBrokenCacheEntry, err)
+	}
+
+	return value, nil
+}
+
+// shouldRefresh implements XFetch: the closer the expiration and the longer the value
+// took to load, the higher the chance that this caller recomputes it ahead of time.
+func (c *Cache[T]) shouldRefresh(entry cacheEntry) bool {
+	if !c.config.earlyRefresh || entry.delta <= 0 || entry.expiresAt.IsZero() {
+		return false
+	}
+
+	gap := -float64(entry.delta) * c.config.earlyRefreshBeta * math.Log(1-rand.Float64())
+
+	return time.Now().Add(time.Duration(gap)).After(entry.expiresAt)
+}
+
+func (c *Cache[T]) key(key string) string {
+	if c.config.namespace == "" {
+		return key
+	}
+
+	return c.config.namespace + ":" + key
+}
+
+// encodeCacheEntry writes the entry as: kind (1 byte), load duration in ms (8 bytes),
+// expiration as unix ms (8 bytes) followed by the encoded value.
+func encodeCacheEntry(entry cacheEntry) []byte {
+	data := make([]byte, cacheHeaderSize+len(entry.payload))
+
+	data[0] = entry.kind
+	binary.BigEndian.PutUint64(data[1:9], uint64(entry.delta.Milliseconds()))
+	binary.BigEndian.PutUint64(data[9:17], uint64(entry.expiresAt.UnixMilli()))
+	copy(data[cacheHeaderSize:], entry.payload)
+
+	return data
+}
+
+func decodeCacheEntry(data []byte) (cacheEntry, error) {
+	if len(data) < cacheHeaderSize {
+		return cacheEntry{}, ErrBrokenCacheEntry
+	}
+
+	kind := data[0]
+	if kind != cacheEntryValue && kind != cacheEntryNegative {
+		return cacheEntry{}, ErrBrokenCacheEntry
+	}
+
+	return cacheEntry{
+		kind:      kind,
+		delta:     time.Duration(binary.BigEndian.Uint64(data[1:9])) * time.Millisecond,
+		expiresAt: time.UnixMilli(int64(binary.BigEndian.Uint64(data[9:17]))),
+		payload:   data[cacheHeaderSize:],
+	}, nil
+}
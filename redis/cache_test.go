@@ -0,0 +1,212 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+type cachedUser struct {
+	ID   int    `json:"id" msgpack:"id"`
+	Name string `json:"name" msgpack:"name"`
+}
+
+func newTestClient(t *testing.T) (*Client, *miniredis.Miniredis) {
+	t.Helper()
+
+	srv := miniredis.RunT(t)
+
+	client := &Client{UniversalClient: redis.NewClient(&redis.Options{Addr: srv.Addr()})}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client, srv
+}
+
+func TestCache_GetOrLoad(t *testing.T) {
+	client, srv := newTestClient(t)
+	ctx := context.Background()
+
+	for _, codec := range []Codec{JSONCodec, MsgpackCodec} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			cache := NewCache[cachedUser](client, WithNamespace(codec.Name()), WithCodec(codec))
+
+			var calls int32
+			loader := func(ctx context.Context) (cachedUser, error) {
+				atomic.AddInt32(&calls, 1)
+				return cachedUser{ID: 1, Name: "john"}, nil
+			}
+
+			user, err := cache.GetOrLoad(ctx, "user:1", time.Minute, loader)
+			require.NoError(t, err)
+			require.Equal(t, cachedUser{ID: 1, Name: "john"}, user)
+
+			user, err = cache.GetOrLoad(ctx, "user:1", time.Minute, loader)
+			require.NoError(t, err)
+			require.Equal(t, cachedUser{ID: 1, Name: "john"}, user)
+			require.EqualValues(t, 1, atomic.LoadInt32(&calls))
+			require.True(t, srv.Exists(codec.Name()+":user:1"))
+
+			require.NoError(t, cache.Delete(ctx, "user:1"))
+
+			_, err = cache.Get(ctx, "user:1")
+			require.ErrorIs(t, err, ErrCacheMiss)
+		})
+	}
+}
+
+func TestCache_GetOrLoadDeduplicatesLoads(t *testing.T) {
+	client, _ := newTestClient(t)
+	cache := NewCache[int](client)
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			value, err := cache.GetOrLoad(context.Background(), "answer", time.Minute, loader)
+			require.NoError(t, err)
+			require.Equal(t, 42, value)
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.EqualValues(t, 1, atomic.LoadInt32(&calls))
+}
+
+func TestCache_GetOrLoadSharedLoad(t *testing.T) {
+	client, _ := newTestClient(t)
+	cache := NewCache[int](client)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (int, error) {
+		close(started)
+		<-release
+
+		return 42, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+
+	go func() {
+		_, err := cache.GetOrLoad(ctx, "answer", time.Minute, loader)
+		first <- err
+	}()
+
+	<-started
+
+	second := make(chan int, 1)
+
+	go func() {
+		value, err := cache.GetOrLoad(context.Background(), "answer", time.Minute, loader)
+		require.NoError(t, err)
+		second <- value
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-first:
+		require.ErrorIs(t, err, context.Canceled, "the cancelled caller doesn't wait for the load")
+	case <-time.After(time.Second):
+		t.Fatal("cancelled caller waits for the shared load")
+	}
+
+	close(release)
+
+	require.Equal(t, 42, <-second, "the load isn't cancelled with the first caller")
+}
+
+func TestCache_GetOrLoadNilInterface(t *testing.T) {
+	client, _ := newTestClient(t)
+	cache := NewCache[any](client)
+
+	value, err := cache.GetOrLoad(context.Background(), "nil", time.Minute, func(ctx context.Context) (any, error) {
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
+
+func TestCache_NegativeCaching(t *testing.T) {
+	client, _ := newTestClient(t)
+	cache := NewCache[cachedUser](client, WithNegativeTTL(time.Minute))
+	ctx := context.Background()
+
+	var calls int32
+	loader := func(ctx context.Context) (cachedUser, error) {
+		atomic.AddInt32(&calls, 1)
+		return cachedUser{}, errors.Join(ErrNotFound, errors.New("no rows"))
+	}
+
+	_, err := cache.GetOrLoad(ctx, "user:404", time.Minute, loader)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = cache.GetOrLoad(ctx, "user:404", time.Minute, loader)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	_, err = cache.Get(ctx, "user:404")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCache_ShouldRefresh(t *testing.T) {
+	cache := NewCache[int](nil, WithEarlyRefresh(1))
+
+	require.False(t, cache.shouldRefresh(cacheEntry{
+		delta:     time.Millisecond,
+		expiresAt: time.Now().Add(time.Hour),
+	}))
+	require.True(t, cache.shouldRefresh(cacheEntry{
+		delta:     time.Millisecond,
+		expiresAt: time.Now().Add(-time.Second),
+	}))
+
+	cache = NewCache[int](nil)
+	require.False(t, cache.shouldRefresh(cacheEntry{
+		delta:     time.Millisecond,
+		expiresAt: time.Now().Add(-time.Second),
+	}))
+}
+
+func TestCacheEntry_EncodeDecode(t *testing.T) {
+	entry := cacheEntry{
+		kind:      cacheEntryValue,
+		delta:     150 * time.Millisecond,
+		expiresAt: time.UnixMilli(time.Now().UnixMilli()),
+		payload:   []byte(`{"id":1}`),
+	}
+
+	decoded, err := decodeCacheEntry(encodeCacheEntry(entry))
+	require.NoError(t, err)
+	require.Equal(t, entry.kind, decoded.kind)
+	require.Equal(t, entry.delta, decoded.delta)
+	require.True(t, entry.expiresAt.Equal(decoded.expiresAt))
+	require.Equal(t, entry.payload, decoded.payload)
+
+	_, err = decodeCacheEntry([]byte("plain value"))
+	require.ErrorIs(t, err, ErrBrokenCacheEntry)
+}
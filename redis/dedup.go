@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultDedupPrefix = "nats:dedup:"
+
+	dedupInProgress = "in_progress"
+	dedupDone       = "done"
+)
+
+// dedupClaimScript sets the key if it doesn't exist and returns the previous value.
+// KEYS[1] - ID key. ARGV: in progress value, ttl_ms.
+var dedupClaimScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return ""
+end
+
+return redis.call("GET", KEYS[1])
+`)
+
+// DedupStore keeps IDs of handled messages in keys with TTL. It implements
+// the DedupStore of the nats module, so duplicates are detected by all instances.
+type DedupStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewDedupStore returns the store prefixing keys with the prefix, "nats:dedup:" if empty.
+func NewDedupStore(client redis.Cmdable, prefix string) *DedupStore {
+	if prefix == "" {
+		prefix = defaultDedupPrefix
+	}
+
+	return &DedupStore{client: client, prefix: prefix}
+}
+
+// Claim sets the ID in progress and reports true if the key doesn't exist.
+// Otherwise it reports false and whether the message is handled.
+func (s *DedupStore) Claim(ctx context.Context, id string, ttl time.Duration) (bool, bool, error) {
+	state, err := dedupClaimScript.Run(ctx, s.client, []string{s.prefix + id}, dedupInProgress, ttl.Milliseconds()).Text()
+	if err != nil {
+		return false, false, fmt.Errorf("failed to claim message id due to error: %w", err)
+	}
+
+	return state == "", state == dedupDone, nil
+}
+
+// Complete sets the ID handled for the ttl.
+func (s *DedupStore) Complete(ctx context.Context, id string, ttl time.Duration) error {
+	if err := s.client.Set(ctx, s.prefix+id, dedupDone, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to complete message id due to error: %w", err)
+	}
+
+	return nil
+}
+
+func (s *DedupStore) Remove(ctx context.Context, id string) error {
+	if err := s.client.Del(ctx, s.prefix+id).Err(); err != nil {
+		return fmt.Errorf("failed to remove message id due to error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupStore(t *testing.T) {
+	ctx := context.Background()
+	client, server := newTestClient(t)
+
+	store := NewDedupStore(client, "")
+
+	claimed, done, err := store.Claim(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	require.False(t, done)
+	require.True(t, server.Exists("nats:dedup:a"))
+
+	claimed, done, err = store.Claim(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	require.False(t, claimed)
+	require.False(t, done, "claimed ID is in progress")
+
+	require.NoError(t, store.Complete(ctx, "a", time.Hour))
+
+	server.FastForward(2 * time.Minute)
+
+	claimed, done, err = store.Claim(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	require.False(t, claimed)
+	require.True(t, done, "completed ID is kept for its ttl")
+
+	server.FastForward(time.Hour)
+
+	claimed, _, err = store.Claim(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	require.True(t, claimed, "expired ID is claimed again")
+
+	require.NoError(t, store.Remove(ctx, "a"))
+	require.False(t, server.Exists("nats:dedup:a"))
+}
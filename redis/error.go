@@ -2,6 +2,20 @@ package redis
 
 import "fmt"
 
+// ConnectionError is returned by NewClient when the server is unreachable after all attempts.
+type ConnectionError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("unable to connect to redis server after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
 func ErrHSet(err error) error {
 	return fmt.Errorf("failed to HSet due to error: %v", err)
 }
@@ -21,3 +35,27 @@ func ErrHDel(err error) error {
 func ErrHExists(err error) error {
 	return fmt.Errorf("failed to HExists due to error: %v", err)
 }
+
+func ErrGet(err error) error {
+	return fmt.Errorf("failed to Get due to error: %w", err)
+}
+
+func ErrSet(err error) error {
+	return fmt.Errorf("failed to Set due to error: %w", err)
+}
+
+func ErrDel(err error) error {
+	return fmt.Errorf("failed to Del due to error: %w", err)
+}
+
+func ErrXAdd(err error) error {
+	return fmt.Errorf("failed to XAdd due to error: %w", err)
+}
+
+func ErrXReadGroup(err error) error {
+	return fmt.Errorf("failed to XReadGroup due to error: %w", err)
+}
+
+func ErrXAck(err error) error {
+	return fmt.Errorf("failed to XAck due to error: %w", err)
+}
@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Kazzess/libraries/tracing"
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const pipelineCommand = "pipeline"
+
+type instrumentationKey struct{}
+
+type instrumentation struct {
+	start time.Time
+	span  trace.Span
+}
+
+// instrumentationHook observes the latency and errors of every command and
+// starts a client span for it when tracing is enabled.
+type instrumentationHook struct {
+	tracing  bool
+	address  string
+	database int
+}
+
+var _ redis.Hook = (*instrumentationHook)(nil)
+
+func newInstrumentationHook(cfg *Config) *instrumentationHook {
+	var address string
+	if len(cfg.addresses) > 0 {
+		address = cfg.addresses[0]
+	}
+
+	return &instrumentationHook{
+		tracing:  cfg.tracing,
+		address:  address,
+		database: cfg.db,
+	}
+}
+
+func (h *instrumentationHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return h.before(ctx, cmd.FullName(), 1), nil
+}
+
+func (h *instrumentationHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+		observeCommandError(cmd.FullName())
+	}
+
+	h.after(ctx, cmd.FullName(), cmd.Err())
+
+	return nil
+}
+
+func (h *instrumentationHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return h.before(ctx, pipelineCommand, len(cmds)), nil
+}
+
+// AfterProcessPipeline counts errors per command, so they aren't counted again for the pipeline.
+func (h *instrumentationHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && !errors.Is(cmdErr, redis.Nil) {
+			observeCommandError(cmd.FullName())
+
+			if err == nil {
+				err = cmdErr
+			}
+		}
+	}
+
+	h.after(ctx, pipelineCommand, err)
+
+	return nil
+}
+
+func (h *instrumentationHook) before(ctx context.Context, command string, size int) context.Context {
+	inst := &instrumentation{start: time.Now()}
+
+	if h.tracing {
+		ctx, inst.span = tracing.Start(
+			ctx,
+			"redis "+command,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", command),
+				attribute.Int("db.redis.database_index", h.database),
+				attribute.String("server.address", h.address),
+				attribute.Int("db.redis.num_cmd", size),
+			),
+		)
+	}
+
+	return context.WithValue(ctx, instrumentationKey{}, inst)
+}
+
+func (h *instrumentationHook) after(ctx context.Context, command string, err error) {
+	inst, ok := ctx.Value(instrumentationKey{}).(*instrumentation)
+	if !ok {
+		return
+	}
+
+	if errors.Is(err, redis.Nil) {
+		err = nil
+	}
+
+	observeCommand(command, err, time.Since(inst.start))
+
+	if inst.span != nil {
+		tracing.Error(ctx, err)
+
+		inst.span.End()
+	}
+}
@@ -2,8 +2,10 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Kazzess/libraries/metrics"
@@ -17,11 +19,130 @@ var (
 		},
 		[]string{"address", "db"},
 	)
+
+	// cacheRequestsTotal is a counter of cache lookups by result.
+	cacheRequestsTotal = metrics.NewCounterVec(
+		metrics.CounterOpts{
+			Name: "redis_cache_requests_total",
+			Help: "The number of cache lookups by result (hit, miss, negative_hit, early_refresh, error)",
+		},
+		[]string{"cache", "result"},
+	)
+
+	// cacheRequestTimeMs is a histogram that measures the time of cache lookups (milliseconds).
+	cacheRequestTimeMs = metrics.NewHistogramVec(
+		metrics.HistogramOpts{
+			Name:    "redis_cache_request_time_ms",
+			Help:    "The time of cache lookups (milliseconds)",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000},
+		},
+		[]string{"cache", "result"},
+	)
+
+	// cacheLoadTimeMs is a histogram that measures the time of loader calls on cache misses (milliseconds).
+	cacheLoadTimeMs = metrics.NewHistogramVec(
+		metrics.HistogramOpts{
+			Name:    "redis_cache_load_time_ms",
+			Help:    "The time of loader calls on cache misses (milliseconds)",
+			Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000},
+		},
+		[]string{"cache", "is_err"},
+	)
+
+	// commandTimeMs is a histogram that measures the time of redis commands (milliseconds).
+	commandTimeMs = metrics.NewHistogramVec(
+		metrics.HistogramOpts{
+			Name:    "redis_command_time_ms",
+			Help:    "The time of redis commands (milliseconds)",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000},
+		},
+		[]string{"command", "is_err"},
+	)
+
+	// commandErrorsTotal is a counter of failed redis commands. redis.Nil is not an error.
+	commandErrorsTotal = metrics.NewCounterVec(
+		metrics.CounterOpts{
+			Name: "redis_command_errors_total",
+			Help: "The number of failed redis commands",
+		},
+		[]string{"command"},
+	)
+
+	// streamProcessingTimeMs is a histogram that measures the time of stream entries processing (milliseconds).
+	streamProcessingTimeMs = metrics.NewHistogramVec(
+		metrics.HistogramOpts{
+			Name:    "redis_stream_message_processing_time_ms",
+			Help:    "The time that consumer process stream entry from read till ack (milliseconds)",
+			Buckets: []float64{1, 10, 25, 50, 100, 150, 200, 500, 1000, 2500, 5000, 10000, 20000, 30000},
+		},
+		[]string{"stream", "group", "is_err"},
+	)
+
+	// streamDeadLettersTotal is a counter of stream entries moved to the dead-letter stream.
+	streamDeadLettersTotal = metrics.NewCounterVec(
+		metrics.CounterOpts{
+			Name: "redis_stream_dead_letters_total",
+			Help: "The number of stream entries moved to the dead-letter stream",
+		},
+		[]string{"stream", "group"},
+	)
 )
 
-func checkRedisAvailability(ctx context.Context, client *Client, cfg *Config) {
-	redisAvailability.WithLabelValues(cfg.address, strconv.Itoa(cfg.db)).Set(0)
+type ObserveWithErr func(err *error)
+
+const (
+	cacheResultHit          = "hit"
+	cacheResultMiss         = "miss"
+	cacheResultNegativeHit  = "negative_hit"
+	cacheResultEarlyRefresh = "early_refresh"
+	cacheResultError        = "error"
+)
+
+func cacheResultFromErr(err error) string {
+	if errors.Is(err, ErrCacheMiss) {
+		return cacheResultMiss
+	}
 
+	return cacheResultError
+}
+
+func observeCache(cache, result string, start time.Time) {
+	cacheRequestsTotal.WithLabelValues(cache, result).Inc()
+	cacheRequestTimeMs.WithLabelValues(cache, result).Observe(float64(time.Since(start).Microseconds()) / 1000)
+}
+
+func observeCacheLoad(cache string, err error, duration time.Duration) {
+	cacheLoadTimeMs.
+		WithLabelValues(cache, strconv.FormatBool(err != nil)).
+		Observe(float64(duration.Microseconds()) / 1000)
+}
+
+func observeCommand(command string, err error, duration time.Duration) {
+	commandTimeMs.
+		WithLabelValues(command, strconv.FormatBool(err != nil)).
+		Observe(float64(duration.Microseconds()) / 1000)
+}
+
+func observeCommandError(command string) {
+	commandErrorsTotal.WithLabelValues(command).Inc()
+}
+
+// observeStreamProcessing observes the time of stream entry processing.
+func observeStreamProcessing(stream, group string) ObserveWithErr {
+	ts := time.Now()
+
+	return func(err *error) {
+		streamProcessingTimeMs.
+			WithLabelValues(stream, group, strconv.FormatBool(*err != nil)).
+			Observe(float64(time.Since(ts).Milliseconds()))
+	}
+}
+
+func observeStreamDeadLetter(stream, group string) {
+	streamDeadLettersTotal.WithLabelValues(stream, group).Inc()
+}
+
+func checkRedisAvailability(ctx context.Context, client *Client, cfg *Config) {
 	go func() {
 		ticker := time.NewTicker(cfg.health.intervalCheck)
 		defer ticker.Stop()
@@ -32,22 +153,26 @@ func checkRedisAvailability(ctx context.Context, client *Client, cfg *Config) {
 				err := client.Ping(ctx).Err()
 				if err != nil {
 					log.Printf("Failed to ping redis server due to error: %v\n", err)
-
-					redisAvailability.WithLabelValues(cfg.address, strconv.Itoa(cfg.db)).Set(0)
-
-					if cfg.health.checker != nil {
-						cfg.health.checker.SetStatus(cfg.health.name, false)
-					}
-				} else {
-					redisAvailability.WithLabelValues(cfg.address, strconv.Itoa(cfg.db)).Set(1)
-
-					if cfg.health.checker != nil {
-						cfg.health.checker.SetStatus(cfg.health.name, true)
-					}
 				}
+
+				setRedisAvailability(cfg, err == nil)
 			case <-ctx.Done():
 				return
 			}
 		}
 	}()
 }
+
+// setRedisAvailability reports the connection state to the gauge and the health checker.
+func setRedisAvailability(cfg *Config, available bool) {
+	var value float64
+	if available {
+		value = 1
+	}
+
+	redisAvailability.WithLabelValues(strings.Join(cfg.addresses, ","), strconv.Itoa(cfg.db)).Set(value)
+
+	if cfg.health.checker != nil {
+		cfg.health.checker.SetStatus(cfg.health.name, available)
+	}
+}
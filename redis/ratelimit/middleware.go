@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kazzess/libraries/apperror"
+	"github.com/Kazzess/libraries/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	HeaderLimit      = "X-RateLimit-Limit"
+	HeaderRemaining  = "X-RateLimit-Remaining"
+	HeaderReset      = "X-RateLimit-Reset"
+	HeaderRetryAfter = "Retry-After"
+
+	defaultErrorMessage = "rate limit exceeded"
+)
+
+// HTTPKeyFunc returns the rate limit key of the request, e.g. user ID or API key.
+// Empty key skips rate limiting of the request.
+type HTTPKeyFunc func(r *http.Request) string
+
+// GRPCKeyFunc returns the rate limit key of the call. Empty key skips rate limiting of the call.
+type GRPCKeyFunc func(ctx context.Context, info *grpc.UnaryServerInfo) string
+
+// HTTPMiddleware rejects requests over the limit with 429 status code and the
+// apperror body. Limiter errors are logged and the request is let through.
+func HTTPMiddleware(limiter *Limiter, limit Limit, keyFunc HTTPKeyFunc, systemCode string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			key := keyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := limiter.Allow(ctx, key, limit)
+			if err != nil {
+				logging.WithAttrs(ctx, logging.ErrAttr(err), logging.StringAttr("key", key)).
+					Error("rate limiter failed")
+
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			for header, value := range resultHeaders(result) {
+				w.Header().Set(header, value)
+			}
+
+			if !result.Allowed {
+				appErr := apperror.NewTooManyRequestsError(
+					systemCode,
+					apperror.WithMessage(defaultErrorMessage),
+				).WithTrace(ctx)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write(appErr.Marshal())
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GRPCUnaryInterceptor rejects calls over the limit with apperror which is converted
+// to codes.ResourceExhausted. Rate limit values are sent in the header metadata.
+// Limiter errors are logged and the call is let through.
+func GRPCUnaryInterceptor(limiter *Limiter, limit Limit, keyFunc GRPCKeyFunc, systemCode string) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		key := keyFunc(ctx, info)
+		if key == "" {
+			return handler(ctx, req)
+		}
+
+		result, err := limiter.Allow(ctx, key, limit)
+		if err != nil {
+			logging.WithAttrs(ctx, logging.ErrAttr(err), logging.StringAttr("key", key)).
+				Error("rate limiter failed")
+
+			return handler(ctx, req)
+		}
+
+		md := metadata.MD{}
+		for header, value := range resultHeaders(result) {
+			md.Set(header, value)
+		}
+
+		if headerErr := grpc.SetHeader(ctx, md); headerErr != nil {
+			logging.WithAttrs(ctx, logging.ErrAttr(headerErr)).Warn("failed to set rate limit headers")
+		}
+
+		if !result.Allowed {
+			return nil, apperror.NewTooManyRequestsError(
+				systemCode,
+				apperror.WithMessage(defaultErrorMessage),
+			).WithTrace(ctx)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func resultHeaders(result Result) map[string]string {
+	headers := map[string]string{
+		HeaderLimit:     strconv.Itoa(result.Limit.Rate),
+		HeaderRemaining: strconv.Itoa(result.Remaining),
+		HeaderReset:     strconv.Itoa(ceilSeconds(result.ResetAfter)),
+	}
+
+	if !result.Allowed && result.RetryAfter >= 0 {
+		headers[HeaderRetryAfter] = strconv.Itoa(ceilSeconds(result.RetryAfter))
+	}
+
+	return headers
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,210 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	myredis "github.com/Kazzess/libraries/redis"
+)
+
+const defaultPrefix = "rate"
+
+var (
+	ErrInvalidLimit = errors.New("rate limit must have positive rate and period")
+	ErrInvalidCost  = errors.New("rate limit cost must be positive")
+)
+
+type Algorithm uint8
+
+const (
+	// GCRA is the generic cell rate algorithm: a token bucket of Burst tokens
+	// refilled with Rate tokens per Period.
+	GCRA Algorithm = iota
+	// FixedWindow allows Rate requests per Period window aligned to the first request.
+	FixedWindow
+	// SlidingWindowLog allows Rate requests during any Period, storing a timestamp per request.
+	SlidingWindowLog
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case GCRA:
+		return "gcra"
+	case FixedWindow:
+		return "fixed_window"
+	case SlidingWindowLog:
+		return "sliding_window_log"
+	default:
+		return "unknown"
+	}
+}
+
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	// Burst is used by GCRA only. Zero value means Rate.
+	Burst int
+}
+
+func PerSecond(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Second}
+}
+
+func PerMinute(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Minute}
+}
+
+func PerHour(rate int) Limit {
+	return Limit{Rate: rate, Period: time.Hour}
+}
+
+type Result struct {
+	Limit Limit
+	// Allowed reports whether the request fits into the limit.
+	Allowed bool
+	// Remaining is the number of requests that can be done right now.
+	Remaining int
+	// RetryAfter is the time to wait before the request is allowed, -1 if allowed.
+	RetryAfter time.Duration
+	// ResetAfter is the time after which the limiter returns to the initial state.
+	ResetAfter time.Duration
+}
+
+type Config struct {
+	algorithm Algorithm
+	prefix    string
+}
+
+type Option func(*Config)
+
+// WithAlgorithm sets the algorithm, GCRA is used by default.
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(cfg *Config) {
+		cfg.algorithm = algorithm
+	}
+}
+
+// WithPrefix sets the key prefix, the default one is "rate".
+func WithPrefix(prefix string) Option {
+	return func(cfg *Config) {
+		cfg.prefix = prefix
+	}
+}
+
+type Limiter struct {
+	client *myredis.Client
+	config *Config
+}
+
+func New(client *myredis.Client, options ...Option) *Limiter {
+	config := &Config{
+		algorithm: GCRA,
+		prefix:    defaultPrefix,
+	}
+
+	for _, o := range options {
+		o(config)
+	}
+
+	return &Limiter{
+		client: client,
+		config: config,
+	}
+}
+
+// Allow is shorthand for AllowN(ctx, key, limit, 1).
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	return l.AllowN(ctx, key, limit, 1)
+}
+
+// AllowN reports whether n requests may happen now and consumes the quota if so.
+func (l *Limiter) AllowN(ctx context.Context, key string, limit Limit, n int) (Result, error) {
+	if limit.Rate <= 0 || limit.Period <= 0 {
+		return Result{}, ErrInvalidLimit
+	}
+
+	if n <= 0 {
+		return Result{}, ErrInvalidCost
+	}
+
+	var (
+		res []interface{}
+		err error
+	)
+
+	fullKey := l.key(key)
+	periodMs := limit.Period.Milliseconds()
+
+	switch l.config.algorithm {
+	case FixedWindow:
+		res, err = fixedWindowScript.Run(ctx, l.client, []string{fullKey}, limit.Rate, periodMs, n).Slice()
+	case SlidingWindowLog:
+		res, err = slidingWindowLogScript.Run(ctx, l.client, []string{fullKey}, limit.Rate, periodMs, n, requestID()).Slice()
+	case GCRA:
+		burst := limit.Burst
+		if burst <= 0 {
+			burst = limit.Rate
+		}
+
+		res, err = gcraScript.Run(ctx, l.client, []string{fullKey}, burst, limit.Rate, periodMs, n).Slice()
+	default:
+		return Result{}, fmt.Errorf("unknown rate limit algorithm %d", l.config.algorithm)
+	}
+
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to run %s rate limit script due to error: %w", l.config.algorithm, err)
+	}
+
+	return parseResult(limit, res)
+}
+
+// Reset removes the state of the key.
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	if err := l.client.Del(ctx, l.key(key)).Err(); err != nil {
+		return myredis.ErrDel(err)
+	}
+
+	return nil
+}
+
+func (l *Limiter) key(key string) string {
+	return l.config.prefix + ":" + l.config.algorithm.String() + ":" + key
+}
+
+func parseResult(limit Limit, res []interface{}) (Result, error) {
+	if len(res) != 4 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v", res)
+	}
+
+	values := make([]int64, len(res))
+	for i, v := range res {
+		value, ok := v.(int64)
+		if !ok {
+			return Result{}, fmt.Errorf("unexpected rate limit script result %v", res)
+		}
+
+		values[i] = value
+	}
+
+	result := Result{
+		Limit:      limit,
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: -1,
+		ResetAfter: time.Duration(values[3]) * time.Millisecond,
+	}
+
+	if values[2] >= 0 {
+		result.RetryAfter = time.Duration(values[2]) * time.Millisecond
+	}
+
+	return result, nil
+}
+
+func requestID() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + strconv.FormatInt(rand.Int63(), 36)
+}
@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+
+	myredis "github.com/Kazzess/libraries/redis"
+)
+
+func newTestLimiter(t *testing.T, algorithm Algorithm) *Limiter {
+	t.Helper()
+
+	srv := miniredis.RunT(t)
+
+	client := &myredis.Client{UniversalClient: redis.NewClient(&redis.Options{Addr: srv.Addr()})}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return New(client, WithAlgorithm(algorithm))
+}
+
+func TestLimiter_AllowN(t *testing.T) {
+	for _, algorithm := range []Algorithm{GCRA, FixedWindow, SlidingWindowLog} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			limiter := newTestLimiter(t, algorithm)
+			ctx := context.Background()
+			limit := PerMinute(3)
+
+			for i := 2; i >= 0; i-- {
+				result, err := limiter.Allow(ctx, "user:1", limit)
+				require.NoError(t, err)
+				require.True(t, result.Allowed)
+				require.Equal(t, i, result.Remaining)
+				require.Equal(t, time.Duration(-1), result.RetryAfter)
+			}
+
+			result, err := limiter.Allow(ctx, "user:1", limit)
+			require.NoError(t, err)
+			require.False(t, result.Allowed)
+			require.Equal(t, 0, result.Remaining)
+			require.Greater(t, result.RetryAfter, time.Duration(0))
+			require.LessOrEqual(t, result.RetryAfter, time.Minute)
+
+			result, err = limiter.Allow(ctx, "user:2", limit)
+			require.NoError(t, err)
+			require.True(t, result.Allowed)
+
+			require.NoError(t, limiter.Reset(ctx, "user:1"))
+
+			result, err = limiter.AllowN(ctx, "user:1", limit, 3)
+			require.NoError(t, err)
+			require.True(t, result.Allowed)
+			require.Equal(t, 0, result.Remaining)
+		})
+	}
+}
+
+func TestLimiter_InvalidArguments(t *testing.T) {
+	limiter := newTestLimiter(t, GCRA)
+
+	_, err := limiter.Allow(context.Background(), "key", Limit{})
+	require.ErrorIs(t, err, ErrInvalidLimit)
+
+	_, err = limiter.AllowN(context.Background(), "key", PerSecond(1), 0)
+	require.ErrorIs(t, err, ErrInvalidCost)
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	limiter := newTestLimiter(t, FixedWindow)
+
+	handler := HTTPMiddleware(limiter, PerMinute(1), func(r *http.Request) string {
+		return r.Header.Get("X-Api-Key")
+	}, "TEST")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("X-Api-Key", "key")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "1", recorder.Header().Get(HeaderLimit))
+	require.Equal(t, "0", recorder.Header().Get(HeaderRemaining))
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get(HeaderRetryAfter))
+	require.Contains(t, recorder.Body.String(), defaultErrorMessage)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"github.com/go-redis/redis/v8"
+)
+
+// All scripts return {allowed (0|1), remaining, retry_after_ms, reset_after_ms}.
+// retry_after_ms is -1 when the request is allowed.
+
+// fixedWindowScript counts requests in the window started by the first request.
+// KEYS[1] - counter key. ARGV: limit, window_ms, cost.
+var fixedWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	ttl = window
+end
+
+if current + cost > limit then
+	return {0, math.max(limit - current, 0), ttl, ttl}
+end
+
+current = redis.call("INCRBY", KEYS[1], cost)
+if redis.call("PTTL", KEYS[1]) < 0 then
+	redis.call("PEXPIRE", KEYS[1], window)
+	ttl = window
+end
+
+return {1, limit - current, -1, ttl}
+`)
+
+// slidingWindowLogScript keeps a sorted set of request timestamps for the last window.
+// KEYS[1] - log key. ARGV: limit, window_ms, cost, unique request id.
+var slidingWindowLogScript = redis.NewScript(`
+redis.replicate_commands()
+
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local id = ARGV[4]
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+
+if count + cost > limit then
+	local retry_after = window
+	local reset_after = window
+
+	local expiring = redis.call("ZRANGE", KEYS[1], count + cost - limit - 1, count + cost - limit - 1, "WITHSCORES")
+	if expiring[2] then
+		retry_after = tonumber(expiring[2]) + window - now
+	end
+
+	local newest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+	if newest[2] then
+		reset_after = tonumber(newest[2]) + window - now
+	end
+
+	return {0, math.max(limit - count, 0), retry_after, reset_after}
+end
+
+for i = 1, cost do
+	redis.call("ZADD", KEYS[1], now, id .. ":" .. i)
+end
+redis.call("PEXPIRE", KEYS[1], window)
+
+return {1, limit - count - cost, -1, window}
+`)
+
+// gcraScript implements the generic cell rate algorithm which behaves like a token bucket
+// that refills continuously. KEYS[1] - theoretical arrival time key.
+// ARGV: burst, rate, period_ms, cost.
+var gcraScript = redis.NewScript(`
+redis.replicate_commands()
+
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+
+local emission_interval = period / rate
+local increment = emission_interval * cost
+local burst_offset = emission_interval * burst
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + tonumber(time[2]) / 1000
+
+local tat = tonumber(redis.call("GET", KEYS[1]) or now)
+tat = math.max(tat, now)
+
+local new_tat = tat + increment
+local diff = now - (new_tat - burst_offset)
+local remaining = math.floor(diff / emission_interval)
+
+if remaining < 0 then
+	return {0, 0, math.ceil(-diff), math.ceil(tat - now)}
+end
+
+local reset_after = new_tat - now
+if reset_after > 0 then
+	redis.call("SET", KEYS[1], tostring(new_tat), "PX", math.ceil(reset_after))
+end
+
+return {1, remaining, -1, math.ceil(reset_after)}
+`)
@@ -3,27 +3,71 @@ package redis
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"log"
+	"os"
+	"slices"
 	"time"
 
+	"github.com/Kazzess/libraries/core/repeat"
 	"github.com/go-redis/redis/v8"
 )
 
 const (
 	defaultIntervalCheck = 10 * time.Second
 	defaultName          = "redis"
+	defaultMinRetryDelay = 100 * time.Millisecond
+	retryBackoffFactor   = 2
 )
 
 type HealthChecker interface {
 	SetStatus(dependencyName string, status bool)
 }
 
+type Mode uint8
+
+const (
+	// ModeSingle connects to a single node.
+	ModeSingle Mode = iota
+	// ModeSentinel connects to the master discovered through sentinel nodes.
+	ModeSentinel
+	// ModeCluster connects to a cluster using the addresses as the seed list.
+	ModeCluster
+)
+
 type Config struct {
-	address  string
-	password string
-	db       int
-	isTLS    bool
-	health   struct {
+	addresses []string
+	username  string
+	password  string
+	db        int
+	isTLS     bool
+	mode      Mode
+	tracing   bool
+	lazy      bool
+	sentinel  struct {
+		masterName string
+		username   string
+		password   string
+	}
+	tls struct {
+		caFile   string
+		certFile string
+		keyFile  string
+	}
+	pool struct {
+		size         int
+		minIdleConns int
+		timeout      time.Duration
+		idleTimeout  time.Duration
+		maxConnAge   time.Duration
+	}
+	timeouts struct {
+		dial  time.Duration
+		read  time.Duration
+		write time.Duration
+	}
+	health struct {
 		checker       HealthChecker
 		intervalCheck time.Duration
 		name          string
@@ -48,18 +92,125 @@ func WithIntervalCheck(interval time.Duration) Options {
 	}
 }
 
+// WithSentinel switches the client to the sentinel mode. The config address is ignored,
+// the master is discovered through the sentinel addresses.
+func WithSentinel(masterName string, sentinelAddresses ...string) Options {
+	return func(cfg *Config) {
+		cfg.mode = ModeSentinel
+		cfg.sentinel.masterName = masterName
+		cfg.addresses = sentinelAddresses
+	}
+}
+
+// WithSentinelCredentials sets credentials for sentinel nodes if they differ from the master ones.
+func WithSentinelCredentials(username, password string) Options {
+	return func(cfg *Config) {
+		cfg.sentinel.username = username
+		cfg.sentinel.password = password
+	}
+}
+
+// WithCluster switches the client to the cluster mode. The addresses are added
+// to the config address, if it isn't empty, and used as the seed list.
+// DB is ignored in the cluster mode.
+func WithCluster(addresses ...string) Options {
+	return func(cfg *Config) {
+		cfg.mode = ModeCluster
+		cfg.addresses = append(cfg.addresses, addresses...)
+	}
+}
+
+// WithUsername sets the username for ACL authentication.
+func WithUsername(username string) Options {
+	return func(cfg *Config) {
+		cfg.username = username
+	}
+}
+
+// WithPoolSize sets the maximum number of socket connections per node.
+func WithPoolSize(size int) Options {
+	return func(cfg *Config) {
+		cfg.pool.size = size
+	}
+}
+
+// WithMinIdleConns sets the minimum number of idle connections per node.
+func WithMinIdleConns(conns int) Options {
+	return func(cfg *Config) {
+		cfg.pool.minIdleConns = conns
+	}
+}
+
+// WithPoolTimeout sets the time to wait for a free connection when all connections are busy.
+func WithPoolTimeout(timeout time.Duration) Options {
+	return func(cfg *Config) {
+		cfg.pool.timeout = timeout
+	}
+}
+
+// WithIdleTimeout sets the time after which idle connections are closed.
+func WithIdleTimeout(timeout time.Duration) Options {
+	return func(cfg *Config) {
+		cfg.pool.idleTimeout = timeout
+	}
+}
+
+// WithMaxConnAge sets the connection age after which the connection is closed.
+func WithMaxConnAge(age time.Duration) Options {
+	return func(cfg *Config) {
+		cfg.pool.maxConnAge = age
+	}
+}
+
+// WithTimeouts sets dial, read and write timeouts. Zero values keep go-redis defaults.
+func WithTimeouts(dial, read, write time.Duration) Options {
+	return func(cfg *Config) {
+		cfg.timeouts.dial = dial
+		cfg.timeouts.read = read
+		cfg.timeouts.write = write
+	}
+}
+
+// WithTLS enables TLS with a custom CA and an optional client certificate.
+// Empty file names are skipped, so WithTLS("", "", "") uses system CAs.
+func WithTLS(caFile, certFile, keyFile string) Options {
+	return func(cfg *Config) {
+		cfg.isTLS = true
+		cfg.tls.caFile = caFile
+		cfg.tls.certFile = certFile
+		cfg.tls.keyFile = keyFile
+	}
+}
+
+// WithLazyConnect makes NewClient return the client without waiting for the connection.
+// The client keeps connecting in background and reports readiness to the health checker.
+func WithLazyConnect(lazy bool) Options {
+	return func(cfg *Config) {
+		cfg.lazy = lazy
+	}
+}
+
+// WithTracing enables OpenTelemetry spans for redis commands.
+func WithTracing(tracing bool) Options {
+	return func(cfg *Config) {
+		cfg.tracing = tracing
+	}
+}
+
 func NewRedisConfig(address, password string, db int, isTLS bool, opt ...Options) *Config {
 	config := &Config{
-		address:  address,
-		password: password,
-		db:       db,
-		isTLS:    isTLS,
+		addresses: []string{address},
+		password:  password,
+		db:        db,
+		isTLS:     isTLS,
 	}
 
 	for _, o := range opt {
 		o(config)
 	}
 
+	config.addresses = slices.DeleteFunc(config.addresses, func(address string) bool { return address == "" })
+
 	if config.health.checker != nil {
 		if config.health.name == "" {
 			config.health.name = defaultName
@@ -74,43 +225,162 @@ func NewRedisConfig(address, password string, db int, isTLS bool, opt ...Options
 }
 
 type Client struct {
-	*redis.Client
+	redis.UniversalClient
+	// Client is the single node or sentinel client, nil in the cluster mode.
+	//
+	// Deprecated: use the embedded UniversalClient, which works in all modes.
+	Client *redis.Client
 }
 
-// NewClient Returns new redis client
+// NewClient Returns new redis client. It pings the server up to maxAttempts times with
+// exponential backoff capped by maxDelay and returns ConnectionError if all attempts fail.
+// Non-positive maxAttempts means retrying until ctx is done.
 func NewClient(ctx context.Context, maxAttempts int, maxDelay time.Duration, cfg *Config) (*Client, error) {
-	options := &redis.Options{
-		Addr:     cfg.address,
-		Password: cfg.password,
-		DB:       cfg.db,
+	options := &redis.UniversalOptions{
+		Addrs:            cfg.addresses,
+		DB:               cfg.db,
+		Username:         cfg.username,
+		Password:         cfg.password,
+		SentinelUsername: cfg.sentinel.username,
+		SentinelPassword: cfg.sentinel.password,
+		MasterName:       cfg.sentinel.masterName,
+		DialTimeout:      cfg.timeouts.dial,
+		ReadTimeout:      cfg.timeouts.read,
+		WriteTimeout:     cfg.timeouts.write,
+		PoolSize:         cfg.pool.size,
+		MinIdleConns:     cfg.pool.minIdleConns,
+		PoolTimeout:      cfg.pool.timeout,
+		IdleTimeout:      cfg.pool.idleTimeout,
+		MaxConnAge:       cfg.pool.maxConnAge,
 	}
 
 	if cfg.isTLS {
-		options.TLSConfig = &tls.Config{
-			MinVersion: tls.VersionTLS12,
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
 		}
+
+		options.TLSConfig = tlsConfig
 	}
 
-	client := &Client{Client: redis.NewClient(options)}
+	var universal redis.UniversalClient
 
-	checkRedisAvailability(ctx, client, cfg)
+	switch cfg.mode {
+	case ModeSentinel:
+		universal = redis.NewFailoverClient(options.Failover())
+	case ModeCluster:
+		universal = redis.NewClusterClient(options.Cluster())
+	default:
+		universal = redis.NewClient(options.Simple())
+	}
+
+	universal.AddHook(newInstrumentationHook(cfg))
+
+	client := &Client{UniversalClient: universal}
+	client.Client, _ = universal.(*redis.Client)
+
+	setRedisAvailability(cfg, false)
+
+	connect := func() error {
+		var attempts int
+
+		err := repeat.Exec(ctx, func(ctx context.Context) error {
+			attempts++
 
-	err := DoWithAttempts(func() error {
-		pingErr := client.Ping(ctx).Err()
-		if pingErr != nil {
-			log.Printf("Failed to ping redis server due to error: %v\n", pingErr)
-			return pingErr
+			pingErr := client.Ping(ctx).Err()
+			if pingErr != nil {
+				log.Printf("Failed to ping redis server due to error: %v\n", pingErr)
+				return pingErr
+			}
+
+			return nil
+		}, retryOptions(maxAttempts, maxDelay, &attempts)...)
+		if err != nil {
+			return &ConnectionError{Attempts: attempts, Err: err}
 		}
 
+		setRedisAvailability(cfg, true)
+
 		return nil
-	}, maxAttempts, maxDelay)
-	if err != nil {
-		log.Fatal("All attempts are exceeded. Unable to connect to redis server")
 	}
 
+	if cfg.lazy {
+		go func() {
+			if err := connect(); err != nil {
+				log.Printf("Unable to connect to redis server: %v\n", err)
+			}
+		}()
+
+		checkRedisAvailability(ctx, client, cfg)
+
+		return client, nil
+	}
+
+	if err := connect(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	checkRedisAvailability(ctx, client, cfg)
+
 	return client, nil
 }
 
+// retryOptions returns exponential backoff from defaultMinRetryDelay up to maxDelay.
+// attempts is the number of attempts made, retrying stops without a wait after the last one.
+func retryOptions(maxAttempts int, maxDelay time.Duration, attempts *int) []repeat.OptionSetter {
+	minDelay := defaultMinRetryDelay
+	if maxDelay < minDelay {
+		minDelay = maxDelay
+	}
+
+	if maxAttempts <= 0 {
+		maxAttempts = -1
+	}
+
+	return []repeat.OptionSetter{
+		repeat.WithMaxRetries(maxAttempts),
+		repeat.WithErrorHandler(func(error) bool {
+			return maxAttempts == -1 || *attempts < maxAttempts
+		}),
+		repeat.WithMinTimeWait(minDelay),
+		repeat.WithMaxTimeWait(maxDelay),
+		repeat.WithBackoffFactor(retryBackoffFactor),
+	}
+}
+
+func newTLSConfig(cfg *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if cfg.tls.caFile != "" {
+		ca, err := os.ReadFile(cfg.tls.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis CA file due to error: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("failed to parse redis CA file %s", cfg.tls.caFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.tls.certFile != "" || cfg.tls.keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.tls.certFile, cfg.tls.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis client certificate due to error: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Deprecated: DoWithAttempts does not observe the context, use repeat.Exec instead.
 func DoWithAttempts(fn func() error, maxAttempts int, delay time.Duration) error {
 	var err error
 
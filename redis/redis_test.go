@@ -0,0 +1,122 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type testHealthChecker struct {
+	mu     sync.Mutex
+	status map[string]bool
+}
+
+func (hc *testHealthChecker) SetStatus(dependencyName string, status bool) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	hc.status[dependencyName] = status
+}
+
+func (hc *testHealthChecker) Status(dependencyName string) bool {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	return hc.status[dependencyName]
+}
+
+func TestNewClient_ConnectionError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := NewRedisConfig("127.0.0.1:1", "", 0, false)
+
+	client, err := NewClient(ctx, 2, 10*time.Millisecond, cfg)
+	require.Nil(t, client)
+
+	var connErr *ConnectionError
+	require.True(t, errors.As(err, &connErr))
+	require.Equal(t, 2, connErr.Attempts)
+}
+
+func TestNewClient_ConnectionErrorAttempts(t *testing.T) {
+	cfg := NewRedisConfig("127.0.0.1:1", "", 0, false)
+
+	// No wait after the last attempt.
+	start := time.Now()
+
+	_, err := NewClient(context.Background(), 1, time.Second, cfg)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+
+	var connErr *ConnectionError
+	require.True(t, errors.As(err, &connErr))
+	require.Equal(t, 1, connErr.Attempts)
+
+	// Infinite retries report the attempts made until ctx is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err = NewClient(ctx, -1, 10*time.Millisecond, cfg)
+	require.True(t, errors.As(err, &connErr))
+	require.Greater(t, connErr.Attempts, 1)
+}
+
+func TestNewRedisConfig_Cluster(t *testing.T) {
+	cfg := NewRedisConfig("", "", 0, false, WithCluster("node-1:6379", "node-2:6379"))
+	require.Equal(t, ModeCluster, cfg.mode)
+	require.Equal(t, []string{"node-1:6379", "node-2:6379"}, cfg.addresses)
+
+	cfg = NewRedisConfig("node-1:6379", "", 0, false, WithCluster("node-2:6379"))
+	require.Equal(t, []string{"node-1:6379", "node-2:6379"}, cfg.addresses)
+}
+
+func TestNewClient_LazyConnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := miniredis.RunT(t)
+	srv.SetError("LOADING")
+
+	hc := &testHealthChecker{status: make(map[string]bool)}
+	cfg := NewRedisConfig(srv.Addr(), "", 0, false, WithLazyConnect(true), WithHealthChecker("", hc))
+
+	client, err := NewClient(ctx, -1, 10*time.Millisecond, cfg)
+	require.NoError(t, err)
+	require.NotNil(t, client)
+	require.NotNil(t, client.Client, "single node client is kept for compatibility")
+	require.False(t, hc.Status(defaultName))
+
+	srv.SetError("")
+
+	require.Eventually(t, func() bool {
+		return hc.Status(defaultName)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestInstrumentationHook_PipelineErrors(t *testing.T) {
+	client, srv := newTestClient(t)
+	client.AddHook(newInstrumentationHook(&Config{}))
+
+	require.NoError(t, srv.Set("name", "john"))
+
+	pipelineErrors := testutil.ToFloat64(commandErrorsTotal.WithLabelValues(pipelineCommand))
+	incrErrors := testutil.ToFloat64(commandErrorsTotal.WithLabelValues("incr"))
+
+	_, err := client.Pipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Incr(context.Background(), "name")
+		pipe.Get(context.Background(), "name")
+
+		return nil
+	})
+	require.Error(t, err)
+
+	require.Equal(t, incrErrors+1, testutil.ToFloat64(commandErrorsTotal.WithLabelValues("incr")))
+	require.Equal(t, pipelineErrors, testutil.ToFloat64(commandErrorsTotal.WithLabelValues(pipelineCommand)))
+}
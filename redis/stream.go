@@ -0,0 +1,542 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Kazzess/libraries/logging"
+	"github.com/Kazzess/libraries/tracing"
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultStreamBatchSize     = 10
+	defaultStreamBlock         = 5 * time.Second
+	defaultStreamMaxDeliveries = 5
+	defaultStreamClaimMinIdle  = time.Minute
+	defaultStreamClaimInterval = 30 * time.Second
+	defaultStreamMaxRetryDelay = 5 * time.Second
+
+	// Fields added to messages moved to the dead-letter stream.
+	DeadLetterFieldStream     = "dlq_stream"
+	DeadLetterFieldID         = "dlq_id"
+	DeadLetterFieldGroup      = "dlq_group"
+	DeadLetterFieldConsumer   = "dlq_consumer"
+	DeadLetterFieldDeliveries = "dlq_deliveries"
+	DeadLetterFieldError      = "dlq_error"
+)
+
+// StreamMessage is a stream entry delivered to a consumer group.
+type StreamMessage struct {
+	Stream string
+	ID     string
+	Values map[string]interface{}
+	// Deliveries is the number of times the entry was delivered, including the current one.
+	Deliveries int64
+}
+
+// StreamHandler processes a stream message. The message is acknowledged if the handler returns nil.
+type StreamHandler func(ctx context.Context, msg *StreamMessage) error
+
+type StreamConsumerConfig struct {
+	batchSize        int64
+	block            time.Duration
+	maxDeliveries    int64
+	deadLetterStream string
+	claimMinIdle     time.Duration
+	claimInterval    time.Duration
+	startID          string
+	tracing          bool
+}
+
+type StreamConsumerOption func(*StreamConsumerConfig)
+
+// WithStreamBatchSize sets the max number of entries read at once.
+func WithStreamBatchSize(size int64) StreamConsumerOption {
+	return func(cfg *StreamConsumerConfig) {
+		cfg.batchSize = size
+	}
+}
+
+// WithStreamBlock sets how long XREADGROUP waits for new entries.
+func WithStreamBlock(block time.Duration) StreamConsumerOption {
+	return func(cfg *StreamConsumerConfig) {
+		cfg.block = block
+	}
+}
+
+// WithStreamDeadLetter moves entries to the dead-letter stream after maxDeliveries failed
+// deliveries. Empty stream name means "<stream>.dlq".
+func WithStreamDeadLetter(stream string, maxDeliveries int64) StreamConsumerOption {
+	return func(cfg *StreamConsumerConfig) {
+		cfg.deadLetterStream = stream
+		cfg.maxDeliveries = maxDeliveries
+	}
+}
+
+// WithStreamClaim sets how long an entry stays pending before other consumers claim it
+// and how often the consumer looks for such entries.
+func WithStreamClaim(minIdle, interval time.Duration) StreamConsumerOption {
+	return func(cfg *StreamConsumerConfig) {
+		cfg.claimMinIdle = minIdle
+		cfg.claimInterval = interval
+	}
+}
+
+// WithStreamStartID sets the ID the group starts from when it is created, "$" by default.
+func WithStreamStartID(id string) StreamConsumerOption {
+	return func(cfg *StreamConsumerConfig) {
+		cfg.startID = id
+	}
+}
+
+// WithStreamTracing enables consumer spans linked to the producer trace context.
+func WithStreamTracing(tracing bool) StreamConsumerOption {
+	return func(cfg *StreamConsumerConfig) {
+		cfg.tracing = tracing
+	}
+}
+
+type StreamConsumer struct {
+	client   *Client
+	stream   string
+	group    string
+	consumer string
+	config   *StreamConsumerConfig
+}
+
+// NewStreamConsumer creates a consumer of the stream in the group.
+// Consumer name must be unique inside the group, e.g. the pod name.
+func (c *Client) NewStreamConsumer(stream, group, consumer string, options ...StreamConsumerOption) *StreamConsumer {
+	config := &StreamConsumerConfig{
+		batchSize:     defaultStreamBatchSize,
+		block:         defaultStreamBlock,
+		maxDeliveries: defaultStreamMaxDeliveries,
+		claimMinIdle:  defaultStreamClaimMinIdle,
+		claimInterval: defaultStreamClaimInterval,
+		startID:       "$",
+	}
+
+	for _, o := range options {
+		o(config)
+	}
+
+	if config.deadLetterStream == "" {
+		config.deadLetterStream = stream + ".dlq"
+	}
+
+	return &StreamConsumer{
+		client:   c,
+		stream:   stream,
+		group:    group,
+		consumer: consumer,
+		config:   config,
+	}
+}
+
+// AddToStream appends the values to the stream injecting the trace context into the fields.
+// Positive maxLen trims the stream approximately to that length.
+func (c *Client) AddToStream(ctx context.Context, stream string, maxLen int64, values map[string]interface{}) (string, error) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	fields := make(map[string]interface{}, len(values)+len(carrier))
+	for k, v := range carrier {
+		fields[k] = v
+	}
+
+	for k, v := range values {
+		fields[k] = v
+	}
+
+	id, err := c.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: maxLen,
+		Approx: maxLen > 0,
+		Values: fields,
+	}).Result()
+	if err != nil {
+		return "", ErrXAdd(err)
+	}
+
+	return id, nil
+}
+
+// CreateGroup creates the consumer group and the stream if they do not exist.
+func (s *StreamConsumer) CreateGroup(ctx context.Context) error {
+	err := s.client.XGroupCreateMkStream(ctx, s.stream, s.group, s.config.startID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create group %s for stream %s due to error: %w", s.group, s.stream, err)
+	}
+
+	return nil
+}
+
+// Run creates the group and processes entries until ctx is done. Entries left pending
+// by this consumer are processed first, entries of dead consumers are claimed periodically.
+// Read errors are logged and retried with backoff, Run returns only if the group is deleted.
+func (s *StreamConsumer) Run(ctx context.Context, handler StreamHandler) error {
+	if err := s.CreateGroup(ctx); err != nil {
+		return err
+	}
+
+	retryDelay := defaultMinRetryDelay
+
+	// "0" reads the pending entries of this consumer left after a restart.
+	for {
+		err := s.readPending(ctx, handler)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+
+		if !s.retry(ctx, err, &retryDelay) {
+			return err
+		}
+	}
+
+	retryDelay = defaultMinRetryDelay
+	lastClaim := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		if time.Since(lastClaim) >= s.config.claimInterval {
+			if err := s.claim(ctx, handler); err != nil && ctx.Err() == nil {
+				logging.WithAttrs(ctx, logging.ErrAttr(err), logging.StringAttr("stream", s.stream)).
+					Error("failed to claim stale stream entries")
+			}
+
+			lastClaim = time.Now()
+		}
+
+		streams, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.group,
+			Consumer: s.consumer,
+			Streams:  []string{s.stream, ">"},
+			Count:    s.config.batchSize,
+			Block:    s.config.block,
+		}).Result()
+		if err != nil {
+			if err == Nil {
+				continue
+			}
+
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			if !s.retry(ctx, ErrXReadGroup(err), &retryDelay) {
+				return ErrXReadGroup(err)
+			}
+
+			continue
+		}
+
+		retryDelay = defaultMinRetryDelay
+
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				s.process(ctx, handler, message, 1)
+			}
+		}
+	}
+}
+
+// retry logs the read error and waits for the delay, doubling it up to defaultStreamMaxRetryDelay.
+// It returns false if the error is unrecoverable or ctx is done.
+func (s *StreamConsumer) retry(ctx context.Context, err error, delay *time.Duration) bool {
+	// The group was deleted, reading fails until it is created again.
+	if strings.Contains(err.Error(), "NOGROUP") {
+		return false
+	}
+
+	logging.WithAttrs(
+		ctx,
+		logging.ErrAttr(err),
+		logging.StringAttr("stream", s.stream),
+		logging.StringAttr("group", s.group),
+		logging.DurationAttr("retry_delay", *delay),
+	).Error("failed to read stream entries")
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(*delay):
+	}
+
+	*delay = min(*delay*retryBackoffFactor, defaultStreamMaxRetryDelay)
+
+	return true
+}
+
+func (s *StreamConsumer) readPending(ctx context.Context, handler StreamHandler) error {
+	start := "0"
+
+	for {
+		streams, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.group,
+			Consumer: s.consumer,
+			Streams:  []string{s.stream, start},
+			Count:    s.config.batchSize,
+			Block:    -1,
+		}).Result()
+		if err != nil {
+			if err == Nil {
+				return nil
+			}
+
+			return ErrXReadGroup(err)
+		}
+
+		var read int
+
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				read++
+				start = message.ID
+
+				// Entries deleted from the stream come without values.
+				if message.Values == nil {
+					s.ack(ctx, message.ID)
+					continue
+				}
+
+				s.process(ctx, handler, message, s.deliveries(ctx, message.ID))
+			}
+		}
+
+		if read == 0 {
+			return nil
+		}
+	}
+}
+
+func (s *StreamConsumer) claim(ctx context.Context, handler StreamHandler) error {
+	start := "0-0"
+
+	for {
+		messages, next, err := s.autoClaim(ctx, start)
+		if err != nil {
+			return err
+		}
+
+		for _, message := range messages {
+			if message.Values == nil {
+				s.ack(ctx, message.ID)
+				continue
+			}
+
+			s.process(ctx, handler, message, s.deliveries(ctx, message.ID))
+		}
+
+		if next == "0-0" || len(messages) == 0 {
+			return nil
+		}
+
+		start = next
+	}
+}
+
+// autoClaim runs XAUTOCLAIM manually: go-redis v8 expects two elements in the reply
+// while Redis 7 returns three (the last one is the list of deleted IDs).
+func (s *StreamConsumer) autoClaim(ctx context.Context, start string) ([]redis.XMessage, string, error) {
+	reply, err := s.client.Do(
+		ctx,
+		"xautoclaim", s.stream, s.group, s.consumer,
+		s.config.claimMinIdle.Milliseconds(), start,
+		"count", s.config.batchSize,
+	).Slice()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to XAutoClaim due to error: %w", err)
+	}
+
+	if len(reply) < 2 {
+		return nil, "", fmt.Errorf("unexpected XAutoClaim reply %v", reply)
+	}
+
+	next, _ := reply[0].(string)
+
+	entries, _ := reply[1].([]interface{})
+	messages := make([]redis.XMessage, 0, len(entries))
+
+	for _, entry := range entries {
+		message, ok := parseXMessage(entry)
+		if !ok {
+			continue
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, next, nil
+}
+
+func (s *StreamConsumer) deliveries(ctx context.Context, id string) int64 {
+	pending, err := s.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: s.stream,
+		Group:  s.group,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return 1
+	}
+
+	return pending[0].RetryCount
+}
+
+func (s *StreamConsumer) process(ctx context.Context, handler StreamHandler, message redis.XMessage, deliveries int64) {
+	msg := &StreamMessage{
+		Stream:     s.stream,
+		ID:         message.ID,
+		Values:     message.Values,
+		Deliveries: deliveries,
+	}
+
+	// The entry was delivered too many times without ack, e.g. the consumer crashed on it.
+	if s.config.maxDeliveries > 0 && deliveries > s.config.maxDeliveries {
+		s.deadLetter(ctx, msg, fmt.Errorf("max deliveries %d exceeded", s.config.maxDeliveries))
+		return
+	}
+
+	msgCtx := s.messageContext(ctx, msg)
+
+	var span trace.Span
+	if s.config.tracing {
+		msgCtx, span = tracing.Start(
+			msgCtx,
+			"redis stream consume "+s.stream,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", "redis"),
+				attribute.String("messaging.destination.name", s.stream),
+				attribute.String("messaging.consumer.group.name", s.group),
+				attribute.String("messaging.message.id", msg.ID),
+				attribute.Int64("messaging.redis.deliveries", deliveries),
+			),
+		)
+		defer span.End()
+	}
+
+	observer := observeStreamProcessing(s.stream, s.group)
+
+	err := s.handle(msgCtx, handler, msg)
+
+	observer(&err)
+
+	if err != nil {
+		tracing.Error(msgCtx, err)
+
+		logging.WithAttrs(
+			msgCtx,
+			logging.ErrAttr(err),
+			logging.StringAttr("stream", s.stream),
+			logging.StringAttr("id", msg.ID),
+			logging.Int64Attr("deliveries", deliveries),
+		).Error("stream handler error")
+
+		if s.config.maxDeliveries > 0 && deliveries >= s.config.maxDeliveries {
+			s.deadLetter(msgCtx, msg, err)
+		}
+
+		return
+	}
+
+	s.ack(msgCtx, msg.ID)
+}
+
+func (s *StreamConsumer) handle(ctx context.Context, handler StreamHandler, msg *StreamMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("stream handler panic: %v", r)
+		}
+	}()
+
+	return handler(ctx, msg)
+}
+
+// messageContext continues the producer trace stored in the message fields.
+func (s *StreamConsumer) messageContext(ctx context.Context, msg *StreamMessage) context.Context {
+	carrier := propagation.MapCarrier{}
+
+	for k, v := range msg.Values {
+		if value, ok := v.(string); ok {
+			carrier[k] = value
+		}
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+func (s *StreamConsumer) deadLetter(ctx context.Context, msg *StreamMessage, cause error) {
+	values := make(map[string]interface{}, len(msg.Values)+6)
+	for k, v := range msg.Values {
+		values[k] = v
+	}
+
+	values[DeadLetterFieldStream] = s.stream
+	values[DeadLetterFieldID] = msg.ID
+	values[DeadLetterFieldGroup] = s.group
+	values[DeadLetterFieldConsumer] = s.consumer
+	values[DeadLetterFieldDeliveries] = msg.Deliveries
+	values[DeadLetterFieldError] = cause.Error()
+
+	err := s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.config.deadLetterStream,
+		Values: values,
+	}).Err()
+	if err != nil {
+		logging.WithAttrs(ctx, logging.ErrAttr(err), logging.StringAttr("id", msg.ID)).
+			Error("failed to move stream entry to dead-letter stream")
+
+		return
+	}
+
+	observeStreamDeadLetter(s.stream, s.group)
+
+	s.ack(ctx, msg.ID)
+}
+
+func (s *StreamConsumer) ack(ctx context.Context, id string) {
+	if err := s.client.XAck(ctx, s.stream, s.group, id).Err(); err != nil {
+		logging.WithAttrs(ctx, logging.ErrAttr(ErrXAck(err)), logging.StringAttr("id", id)).
+			Error("failed to ack stream entry")
+	}
+}
+
+func parseXMessage(entry interface{}) (redis.XMessage, bool) {
+	parts, ok := entry.([]interface{})
+	if !ok || len(parts) != 2 {
+		return redis.XMessage{}, false
+	}
+
+	id, ok := parts[0].(string)
+	if !ok {
+		return redis.XMessage{}, false
+	}
+
+	// Redis 6.2 returns deleted entries with nil values.
+	fields, ok := parts[1].([]interface{})
+	if !ok {
+		return redis.XMessage{ID: id}, true
+	}
+
+	values := make(map[string]interface{}, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, _ := fields[i].(string)
+		values[key] = fields[i+1]
+	}
+
+	return redis.XMessage{ID: id, Values: values}, true
+}
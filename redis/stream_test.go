@@ -0,0 +1,136 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamConsumer_Run(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	consumer := client.NewStreamConsumer("orders", "billing", "pod-1",
+		WithStreamBlock(10*time.Millisecond), WithStreamStartID("0"))
+
+	_, err := client.AddToStream(ctx, "orders", 0, map[string]interface{}{"order_id": "1"})
+	require.NoError(t, err)
+
+	received := make(chan *StreamMessage, 1)
+
+	go func() {
+		_ = consumer.Run(ctx, func(ctx context.Context, msg *StreamMessage) error {
+			received <- msg
+			return nil
+		})
+	}()
+
+	select {
+	case msg := <-received:
+		require.Equal(t, "1", msg.Values["order_id"])
+		require.EqualValues(t, 1, msg.Deliveries)
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for stream message")
+	}
+
+	require.Eventually(t, func() bool {
+		pending, pendingErr := client.XPending(ctx, "orders", "billing").Result()
+		return pendingErr == nil && pending.Count == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStreamConsumer_DeadLetter(t *testing.T) {
+	client, srv := newTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	consumer := client.NewStreamConsumer("payments", "billing", "pod-1",
+		WithStreamBlock(10*time.Millisecond),
+		WithStreamStartID("0"),
+		WithStreamClaim(time.Millisecond, 10*time.Millisecond),
+		WithStreamDeadLetter("", 2),
+	)
+
+	_, err := client.AddToStream(ctx, "payments", 0, map[string]interface{}{"payment_id": "7"})
+	require.NoError(t, err)
+
+	var calls int32
+
+	go func() {
+		_ = consumer.Run(ctx, func(ctx context.Context, msg *StreamMessage) error {
+			atomic.AddInt32(&calls, 1)
+			return errors.New("payment provider is down")
+		})
+	}()
+
+	require.Eventually(t, func() bool {
+		return srv.Exists("payments.dlq")
+	}, 3*time.Second, 10*time.Millisecond)
+
+	messages, err := client.XRange(ctx, "payments.dlq", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.Equal(t, "7", messages[0].Values["payment_id"])
+	require.Equal(t, "payments", messages[0].Values[DeadLetterFieldStream])
+	require.Equal(t, "payment provider is down", messages[0].Values[DeadLetterFieldError])
+	require.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
+
+func TestStreamConsumer_RunRetriesReadErrors(t *testing.T) {
+	client, srv := newTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	consumer := client.NewStreamConsumer("payments", "billing", "pod-1",
+		WithStreamBlock(10*time.Millisecond), WithStreamStartID("0"))
+
+	require.NoError(t, consumer.CreateGroup(ctx))
+
+	received := make(chan *StreamMessage, 1)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- consumer.Run(ctx, func(ctx context.Context, msg *StreamMessage) error {
+			received <- msg
+			return nil
+		})
+	}()
+
+	// Let the consumer create the group and read the pending entries.
+	time.Sleep(50 * time.Millisecond)
+
+	// Every command fails while the error is set, as on a network failure.
+	srv.SetError("LOADING Redis is loading the dataset in memory")
+	time.Sleep(300 * time.Millisecond)
+	srv.SetError("")
+
+	_, err := client.AddToStream(ctx, "payments", 0, map[string]interface{}{"payment_id": "1"})
+	require.NoError(t, err)
+
+	select {
+	case msg := <-received:
+		require.Equal(t, "1", msg.Values["payment_id"])
+	case err = <-done:
+		t.Fatalf("consumer stopped: %v", err)
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for stream message")
+	}
+
+	// The deleted group is unrecoverable.
+	require.NoError(t, client.XGroupDestroy(ctx, "payments", "billing").Err())
+
+	select {
+	case err = <-done:
+		require.Error(t, err)
+	case <-ctx.Done():
+		t.Fatal("consumer is not stopped after the group is deleted")
+	}
+}